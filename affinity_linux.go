@@ -0,0 +1,51 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+type cpuMask [16]uint64
+
+// PinToCPU locks the calling goroutine to its OS thread and restricts that
+// thread to cpu, so the scheduler can neither move the goroutine to another
+// thread nor the thread to another core. The lock is never released: when the
+// goroutine exits, the runtime discards the thread along with its affinity.
+// Pinning is Linux-only; elsewhere PinToCPU returns an error.
+func PinToCPU(cpu int) error {
+	runtime.LockOSThread()
+	_, err := pinThread(cpu)
+	return err
+}
+
+// pinThread restricts the calling OS thread to cpu and returns a function that
+// restores its previous affinity. The caller must hold runtime.LockOSThread.
+func pinThread(cpu int) (restore func(), err error) {
+	var mask cpuMask
+	if cpu < 0 || cpu >= len(mask)*64 {
+		return nil, fmt.Errorf("ring: cpu %d out of range", cpu)
+	}
+
+	var old cpuMask
+	if err := schedAffinity(syscall.SYS_SCHED_GETAFFINITY, &old); err != nil {
+		return nil, err
+	}
+
+	mask[cpu/64] |= 1 << (cpu % 64)
+	if err := schedAffinity(syscall.SYS_SCHED_SETAFFINITY, &mask); err != nil {
+		return nil, err
+	}
+	return func() { schedAffinity(syscall.SYS_SCHED_SETAFFINITY, &old) }, nil
+}
+
+func schedAffinity(trap uintptr, mask *cpuMask) error {
+	_, _, errno := syscall.RawSyscall(trap, 0, unsafe.Sizeof(*mask), uintptr(unsafe.Pointer(mask)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
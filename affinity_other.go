@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// PinToCPU is only implemented on Linux.
+func PinToCPU(cpu int) error {
+	return errors.New("ring: CPU pinning is only supported on Linux")
+}
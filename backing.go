@@ -0,0 +1,66 @@
+package main
+
+import "fmt"
+
+// Backing holds the four per-slot arrays of a RingBuffer: the cycle stamps
+// and the three payload columns. None of them holds Go pointers, so they may
+// live in memory the garbage collector does not know about, such as an mmap'd
+// region, without the GC ever scanning them.
+type Backing struct {
+	CycleState []cycle
+	IDs        []uint64
+	Prices     []float64
+	Qtys       []uint32
+}
+
+// BackingProvider supplies the arrays behind a buffer built with
+// WithBackingProvider.
+//
+// Ownership: Alloc hands the arrays to the buffer, which uses them until they
+// are passed back to Release. The buffer never keeps a reference to arrays it
+// has released. Resize allocates the new arrays first and releases the old
+// ones once the items have moved over; a failed Resize allocates nothing.
+// The buffer never releases the arrays it is still using: once the last
+// goroutine is done with the buffer, the owner releases Backing() itself, and
+// must not touch the buffer afterwards.
+type BackingProvider interface {
+	// Alloc returns arrays of exactly capacity elements each. Their contents
+	// do not matter; the buffer seeds the cycle stamps itself and never
+	// reads a payload slot before writing it.
+	Alloc(capacity uint64) Backing
+	// Release takes back arrays the buffer no longer uses.
+	Release(Backing)
+}
+
+// WithBackingProvider makes the buffer get its arrays from p instead of the
+// Go heap, in the constructor and in every Resize. See BackingProvider for
+// who owns the arrays when.
+func WithBackingProvider(p BackingProvider) Option {
+	return func(c *bufferConfig) { c.backing = p }
+}
+
+// allocBacking returns arrays of capacity slots from p, or from the heap if p
+// is nil, and panics if p returned arrays of the wrong length.
+func allocBacking(p BackingProvider, capacity uint64) Backing {
+	if p == nil {
+		return Backing{
+			CycleState: make([]cycle, capacity),
+			IDs:        make([]uint64, capacity),
+			Prices:     make([]float64, capacity),
+			Qtys:       make([]uint32, capacity),
+		}
+	}
+	b := p.Alloc(capacity)
+	for _, n := range []int{len(b.CycleState), len(b.IDs), len(b.Prices), len(b.Qtys)} {
+		if uint64(n) != capacity {
+			panic(fmt.Sprintf("ring: BackingProvider returned an array of %d slots for capacity %d", n, capacity))
+		}
+	}
+	return b
+}
+
+// Backing returns the arrays the buffer currently uses. It is meant for a
+// BackingProvider owner releasing them once the buffer is no longer used.
+func (rb *RingBuffer) Backing() Backing {
+	return Backing{CycleState: rb.cycleState, IDs: rb.ids, Prices: rb.prices, Qtys: rb.qtys}
+}
@@ -0,0 +1,108 @@
+package main
+
+import "testing"
+
+// trackingProvider allocates from the heap and records every array it hands
+// out and takes back, keyed by capacity.
+type trackingProvider struct {
+	allocs   []uint64
+	releases []uint64
+	live     map[*uint64]uint64
+}
+
+func (p *trackingProvider) Alloc(capacity uint64) Backing {
+	b := Backing{
+		CycleState: make([]cycle, capacity),
+		IDs:        make([]uint64, capacity),
+		Prices:     make([]float64, capacity),
+		Qtys:       make([]uint32, capacity),
+	}
+	// Garbage in the payload columns must not leak into the buffer.
+	for i := range b.IDs {
+		b.IDs[i] = 0xdead
+	}
+	p.allocs = append(p.allocs, capacity)
+	p.live[&b.IDs[0]] = capacity
+	return b
+}
+
+func (p *trackingProvider) Release(b Backing) {
+	capacity, ok := p.live[&b.IDs[0]]
+	if !ok {
+		panic("released arrays that were never allocated or were released twice")
+	}
+	delete(p.live, &b.IDs[0])
+	p.releases = append(p.releases, capacity)
+}
+
+func TestBackingProvider(t *testing.T) {
+	p := &trackingProvider{live: map[*uint64]uint64{}}
+	rb := NewbufferOpts(8, WithBackingProvider(p))
+	if len(p.allocs) != 1 || p.allocs[0] != 8 {
+		t.Fatalf("allocations after the constructor: %v, want [8]", p.allocs)
+	}
+	if &rb.Backing().IDs[0] != firstKey(p.live) {
+		t.Fatal("buffer does not use the provider's arrays")
+	}
+
+	for i := range uint64(5) {
+		rb.Enqueue(i, float64(i), uint32(i))
+	}
+	if err := rb.Resize(16); err != nil {
+		t.Fatalf("Resize(16): %v", err)
+	}
+	if err := rb.Resize(2); err == nil {
+		t.Fatal("Resize(2) with 5 items pending succeeded")
+	}
+	if len(p.allocs) != 2 || p.allocs[1] != 16 {
+		t.Fatalf("allocations after Resize: %v, want [8 16]", p.allocs)
+	}
+	if len(p.releases) != 1 || p.releases[0] != 8 {
+		t.Fatalf("releases after Resize: %v, want [8]", p.releases)
+	}
+
+	for i := range uint64(5) {
+		o, ok := rb.DequeueOrder()
+		if !ok || o != (Order{ID: i, Price: float64(i), Qty: uint32(i)}) {
+			t.Fatalf("item %d = %+v, %v", i, o, ok)
+		}
+	}
+	if _, ok := rb.DequeueOrder(); ok {
+		t.Fatal("dequeued an item out of the provider's garbage")
+	}
+
+	p.Release(rb.Backing())
+	if len(p.live) != 0 {
+		t.Fatalf("%d allocations never released", len(p.live))
+	}
+}
+
+func TestBackingProviderWrongLength(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("constructor accepted arrays of the wrong length")
+		}
+	}()
+	NewbufferOpts(8, WithBackingProvider(shortProvider{}))
+}
+
+// shortProvider returns one slot too few for the ID column.
+type shortProvider struct{}
+
+func (shortProvider) Alloc(capacity uint64) Backing {
+	return Backing{
+		CycleState: make([]cycle, capacity),
+		IDs:        make([]uint64, capacity-1),
+		Prices:     make([]float64, capacity),
+		Qtys:       make([]uint32, capacity),
+	}
+}
+
+func (shortProvider) Release(Backing) {}
+
+func firstKey(m map[*uint64]uint64) *uint64 {
+	for k := range m {
+		return k
+	}
+	return nil
+}
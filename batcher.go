@@ -0,0 +1,171 @@
+package main
+
+// defaultBatcherBatch is how many items a Batcher stages before it flushes.
+const defaultBatcherBatch = 16
+
+// Batcher batches single-item enqueues for one goroutine. Items are staged
+// in private slices and reach the buffer a batch at a time, through one
+// claim on writeIndex. So the per-item cost is plain stores, and the shared
+// index and the cycle states are touched once per batch instead of once per
+// item. It does not cache the consumers' boundary the way the SPSC buffer
+// does, and could not usefully: consumers release slots out of order, so a
+// free slot is only known from its own cycle state, never from a cached
+// readIndex.
+//
+// The price is latency: staged items are invisible to consumers until the
+// batch fills or Flush is called. Call Flush when a burst ends, and Close
+// when the producer goroutine is done with the Batcher, before the buffer
+// itself is closed; RingBuffer.Close does not know about Batchers, and items
+// still staged when the buffer closes are never written. A Batcher must not
+// be used from more than one goroutine at a time; give each producer
+// goroutine its own.
+type Batcher struct {
+	rb     *RingBuffer
+	ids    []uint64
+	prices []float64
+	qtys   []uint32
+	staged int
+	closed bool
+
+	// batch is how many staged items trigger a flush. It stays between
+	// minBatch and maxBatch, which are equal unless WithAdaptiveBatch is set.
+	batch              int
+	minBatch, maxBatch int
+}
+
+// BatcherOption configures a Batcher built with NewBatcher.
+type BatcherOption func(*Batcher)
+
+// WithBatchSize sets how many items a Batcher stages before flushing,
+// capped at the buffer's capacity. The default is 16.
+func WithBatchSize(n int) BatcherOption {
+	return func(b *Batcher) { b.setBatch(n) }
+}
+
+// WithAdaptiveBatch makes the flush size adapt to how the buffer keeps up,
+// between lo and hi items, starting at lo. A flush that writes everything
+// doubles it, so a buffer with room takes fewer, larger claims; a flush that
+// finds too little room halves it, so a producer facing a nearly full buffer
+// offers batches that fit instead of retrying one that doesn't. Both bounds
+// are capped at the buffer's capacity.
+func WithAdaptiveBatch(lo, hi int) BatcherOption {
+	return func(b *Batcher) {
+		b.setBatch(max(lo, hi))
+		b.minBatch = min(max(lo, 1), b.maxBatch)
+		b.batch = b.minBatch
+	}
+}
+
+// NewBatcher returns a Batcher writing to rb.
+func (rb *RingBuffer) NewBatcher(opts ...BatcherOption) *Batcher {
+	b := &Batcher{rb: rb}
+	b.setBatch(defaultBatcherBatch)
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+func (b *Batcher) setBatch(n int) {
+	n = int(min(uint64(max(n, 1)), b.rb.capacity))
+	b.ids = make([]uint64, n)
+	b.prices = make([]float64, n)
+	b.qtys = make([]uint32, n)
+	b.staged = 0
+	b.batch, b.minBatch, b.maxBatch = n, n, n
+}
+
+// Enqueue stages one item and flushes once a full batch is staged. It returns
+// false when the Batcher or the buffer is closed, or when the staging area is full and the
+// buffer has no room for any of it. With WithAdaptiveBatch the staging area
+// holds the upper bound, while a batch is whatever the flush size currently
+// is.
+func (b *Batcher) Enqueue(id uint64, price float64, qty uint32) bool {
+	if b.closed || b.rb.Closed() {
+		return false
+	}
+	if b.staged == len(b.ids) {
+		if b.Flush(); b.staged == len(b.ids) {
+			return false
+		}
+	}
+
+	b.ids[b.staged] = id
+	b.prices[b.staged] = price
+	b.qtys[b.staged] = qty
+	b.staged++
+	if b.staged >= b.batch {
+		b.Flush()
+	}
+	return true
+}
+
+// EnqueueBatch flushes the staged items and then enqueues the batch with
+// RingBuffer.EnqueueBatch, all or nothing, so the producer's items stay in
+// order. It returns 0 if staged items are still waiting for room, or if the
+// Batcher is closed.
+func (b *Batcher) EnqueueBatch(ids []uint64, prices []float64, qtys []uint32) uint64 {
+	if b.closed {
+		return 0
+	}
+	if b.Flush(); b.staged > 0 {
+		return 0
+	}
+	return b.rb.EnqueueBatch(ids, prices, qtys)
+}
+
+// Flush writes as many staged items as the buffer has room for, oldest
+// first, and returns how many it wrote. Under WithAdaptiveBatch it also
+// moves the flush size.
+func (b *Batcher) Flush() uint64 {
+	if b.staged == 0 {
+		return 0
+	}
+
+	n := b.rb.EnqueueBatchPartial(b.ids[:b.staged], b.prices[:b.staged], b.qtys[:b.staged])
+	if n > 0 {
+		rest := b.staged - int(n)
+		copy(b.ids, b.ids[n:b.staged])
+		copy(b.prices, b.prices[n:b.staged])
+		copy(b.qtys, b.qtys[n:b.staged])
+		b.staged = rest
+	}
+
+	if b.staged == 0 {
+		b.batch = min(b.batch*2, b.maxBatch)
+	} else {
+		b.batch = max(b.batch/2, b.minBatch)
+	}
+	return n
+}
+
+// BatchSize returns how many staged items currently trigger a flush.
+func (b *Batcher) BatchSize() int {
+	return b.batch
+}
+
+// Staged returns how many items are waiting for the next flush.
+func (b *Batcher) Staged() int {
+	return b.staged
+}
+
+// Close flushes the staged items, waiting with the buffer's WaitStrategy
+// while it is full, and retires the Batcher: Enqueue fails from then on.
+// It returns an error wrapping ErrClosed if the buffer was closed with items
+// still staged; those items are dropped. Closing twice does nothing.
+func (b *Batcher) Close() error {
+	b.closed = true
+	for attempt := 0; b.staged > 0; attempt++ {
+		if b.rb.Closed() {
+			lost := b.staged
+			b.staged = 0
+			return b.rb.newError("Batcher.Close", uint64(lost), ErrClosed)
+		}
+		if b.Flush() > 0 {
+			attempt = 0
+			continue
+		}
+		b.rb.wait.Wait(attempt)
+	}
+	return nil
+}
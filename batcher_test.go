@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+)
+
+func TestProducerEnqueueAfterClose(t *testing.T) {
+	rb := Newbuffer(64)
+	b := rb.NewBatcher()
+	if !b.Enqueue(1, 0, 0) {
+		t.Fatal("Enqueue on an open buffer failed")
+	}
+	b.Flush()
+	rb.Close()
+
+	if b.Enqueue(2, 0, 0) {
+		t.Fatal("Enqueue on a closed buffer reported success")
+	}
+	if b.Staged() != 0 {
+		t.Fatalf("Staged() = %d after a rejected Enqueue, want 0", b.Staged())
+	}
+	if o, ok := rb.DequeueOrder(); !ok || o.ID != 1 {
+		t.Fatalf("DequeueOrder = %+v, %v, want ID 1", o, ok)
+	}
+}
+
+func TestProducerFlushDeliversInOrder(t *testing.T) {
+	rb := Newbuffer(64)
+	b := rb.NewBatcher(WithBatchSize(8))
+	for i := range uint64(20) {
+		if !b.Enqueue(i, 0, 0) {
+			t.Fatalf("Enqueue(%d) failed", i)
+		}
+	}
+	if b.Staged() != 4 || rb.Len() != 16 {
+		t.Fatalf("Staged() = %d, Len() = %d, want 4 and 16", b.Staged(), rb.Len())
+	}
+	b.Flush()
+	for i := range uint64(20) {
+		if o, ok := rb.DequeueOrder(); !ok || o.ID != i {
+			t.Fatalf("item %d = %+v, %v", i, o, ok)
+		}
+	}
+}
+
+// TestBatcherCloseFlushes checks that Close writes a partial batch, waiting
+// for a consumer to make room when the buffer is full, and that the Batcher
+// refuses items afterwards.
+func TestBatcherCloseFlushes(t *testing.T) {
+	rb := Newbuffer(8)
+	for i := range uint64(6) {
+		rb.Enqueue(i, 0, 0)
+	}
+	b := rb.NewBatcher(WithBatchSize(8))
+	for i := range uint64(5) {
+		b.Enqueue(6+i, 0, 0)
+	}
+	if b.Staged() != 5 {
+		t.Fatalf("Staged() = %d, want 5", b.Staged())
+	}
+
+	consumed := make(chan []uint64)
+	go func() {
+		var ids []uint64
+		for len(ids) < 11 {
+			if o, ok := rb.DequeueOrder(); ok {
+				ids = append(ids, o.ID)
+			} else {
+				runtime.Gosched()
+			}
+		}
+		consumed <- ids
+	}()
+
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if b.Staged() != 0 {
+		t.Fatalf("Staged() = %d after Close, want 0", b.Staged())
+	}
+	ids := <-consumed
+	for i, id := range ids {
+		if id != uint64(i) {
+			t.Fatalf("item %d has ID %d", i, id)
+		}
+	}
+	if b.Enqueue(99, 0, 0) || b.EnqueueBatch([]uint64{99}, []float64{0}, []uint32{0}) != 0 {
+		t.Fatal("closed Batcher accepted an item")
+	}
+	if err := b.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+}
+
+func TestBatcherCloseAfterBufferClosed(t *testing.T) {
+	rb := Newbuffer(8)
+	b := rb.NewBatcher()
+	b.Enqueue(1, 0, 0)
+	b.Enqueue(2, 0, 0)
+	rb.Close()
+
+	var be *BufferError
+	if err := b.Close(); !errors.Is(err, ErrClosed) || !errors.As(err, &be) || be.Attempted != 2 {
+		t.Fatalf("Close = %v, want ErrClosed with 2 items attempted", err)
+	}
+	if !rb.IsEmpty() {
+		t.Fatalf("Len() = %d, want the staged items dropped", rb.Len())
+	}
+}
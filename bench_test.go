@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// These are the go test -bench counterparts of the runs in main.go. The
+// binary's runs print a fixed report for a fixed workload; these scale with
+// b.N, so benchstat can compare them across changes:
+//
+//	go test -run '^$' -bench . -count 10 > new.txt
+
+// mpmcShape is one producer/consumer/batch combination of an MPMC benchmark.
+type mpmcShape struct {
+	producers, consumers, batch int
+}
+
+func (s mpmcShape) String() string {
+	return fmt.Sprintf("P%d_C%d_B%d", s.producers, s.consumers, s.batch)
+}
+
+var mpmcShapes = []mpmcShape{
+	{1, 1, 1}, {1, 1, 16},
+	{4, 4, 1}, {4, 4, 16},
+	{8, 1, 16}, {1, 8, 16},
+}
+
+// benchMPMC moves about b.N items from s.producers to s.consumers and
+// reports the throughput as ops/s. Each worker gets its own function from
+// newProducer or newConsumer: a producer function writes n items, and a
+// consumer function takes what it can and returns how many, 0 if nothing was
+// ready. producersDone, if not nil, runs once every producer has returned.
+func benchMPMC(b *testing.B, s mpmcShape, newProducer func() func(n int), newConsumer func() func() int, producersDone func()) {
+	perProducer := (b.N + s.producers - 1) / s.producers
+	perProducer = (perProducer + s.batch - 1) / s.batch * s.batch
+	total := uint64(perProducer * s.producers)
+
+	var consumed atomic.Uint64
+	var producers, consumers sync.WaitGroup
+	producers.Add(s.producers)
+	consumers.Add(s.consumers)
+
+	b.ResetTimer()
+	for range s.producers {
+		produce := newProducer()
+		go func() {
+			defer producers.Done()
+			produce(perProducer)
+		}()
+	}
+	for range s.consumers {
+		consume := newConsumer()
+		go func() {
+			defer consumers.Done()
+			for consumed.Load() < total {
+				if n := consume(); n > 0 {
+					consumed.Add(uint64(n))
+				} else {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+
+	producers.Wait()
+	if producersDone != nil {
+		producersDone()
+	}
+	consumers.Wait()
+	b.StopTimer()
+	b.ReportMetric(float64(total)/b.Elapsed().Seconds(), "ops/s")
+}
+
+func BenchmarkRingBufferMPMC(b *testing.B) {
+	for _, s := range mpmcShapes {
+		b.Run(s.String(), func(b *testing.B) {
+			rb := Newbuffer(BufferSize)
+			benchMPMC(b, s,
+				func() func(int) {
+					ids := make([]uint64, s.batch)
+					prices := make([]float64, s.batch)
+					qtys := make([]uint32, s.batch)
+					return func(n int) {
+						for i := 0; i < n; i += s.batch {
+							for rb.EnqueueBatch(ids, prices, qtys) == 0 {
+								runtime.Gosched()
+							}
+						}
+					}
+				},
+				func() func() int {
+					ids := make([]uint64, s.batch)
+					prices := make([]float64, s.batch)
+					qtys := make([]uint32, s.batch)
+					return func() int {
+						return int(rb.DequeueBatchUpTo(ids, prices, qtys))
+					}
+				},
+				nil)
+		})
+	}
+}
+
+// BenchmarkChannelMPMC is BenchmarkRingBufferMPMC over a buffered channel. A
+// channel has no batch operations, so the batch size only rounds the item
+// count; it keeps the sub-benchmark names aligned for benchstat.
+func BenchmarkChannelMPMC(b *testing.B) {
+	for _, s := range mpmcShapes {
+		b.Run(s.String(), func(b *testing.B) {
+			ch := make(chan Order, BufferSize)
+			benchMPMC(b, s,
+				func() func(int) {
+					return func(n int) {
+						for i := range n {
+							ch <- Order{ID: uint64(i), Price: 100.0, Qty: 1}
+						}
+					}
+				},
+				func() func() int {
+					return func() int {
+						// A closed, drained channel returns 0 like an empty
+						// ring, and the worker stops once the total is in.
+						if _, ok := <-ch; ok {
+							return 1
+						}
+						return 0
+					}
+				},
+				func() { close(ch) })
+		})
+	}
+}
+
+// BenchmarkEnqueueSingle times Enqueue on its own. The buffer is drained with
+// the timer stopped whenever it fills.
+func BenchmarkEnqueueSingle(b *testing.B) {
+	rb := Newbuffer(BufferSize)
+	b.ResetTimer()
+	for i := range b.N {
+		if !rb.Enqueue(uint64(i), 100.0, 1) {
+			b.StopTimer()
+			for rb.DequeueInto(new(Order)) {
+			}
+			b.StartTimer()
+			rb.Enqueue(uint64(i), 100.0, 1)
+		}
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "ops/s")
+}
+
+// BenchmarkDequeueSingle times Dequeue on its own. The buffer is refilled with
+// the timer stopped whenever it empties.
+func BenchmarkDequeueSingle(b *testing.B) {
+	rb := Newbuffer(BufferSize)
+	var o Order
+	b.ResetTimer()
+	for range b.N {
+		if !rb.DequeueInto(&o) {
+			b.StopTimer()
+			for j := uint64(0); rb.Enqueue(j, 100.0, 1); j++ {
+			}
+			b.StartTimer()
+			rb.DequeueInto(&o)
+		}
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "ops/s")
+}
+
+// BenchmarkEnqueueDequeueParallel has every goroutine of b.RunParallel
+// enqueue an item and dequeue one, so all of them contend on both indices.
+func BenchmarkEnqueueDequeueParallel(b *testing.B) {
+	rb := Newbuffer(BufferSize)
+	b.RunParallel(func(pb *testing.PB) {
+		var o Order
+		for pb.Next() {
+			for !rb.Enqueue(1, 100.0, 1) {
+				runtime.Gosched()
+			}
+			for !rb.DequeueInto(&o) {
+				runtime.Gosched()
+			}
+		}
+	})
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "ops/s")
+}
+
+// BenchmarkChannelParallel is BenchmarkEnqueueDequeueParallel over a buffered
+// channel, for comparison.
+func BenchmarkChannelParallel(b *testing.B) {
+	ch := make(chan Order, BufferSize)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			ch <- Order{ID: 1, Price: 100.0, Qty: 1}
+			<-ch
+		}
+	})
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "ops/s")
+}
+
+// BenchmarkSingleItemRoundTrip times one enqueue and one dequeue with each
+// flavour of the single-item API and reports allocations; every sub-benchmark
+// should read 0 allocs/op.
+func BenchmarkSingleItemRoundTrip(b *testing.B) {
+	rb := Newbuffer(BufferSize)
+	rounds := []struct {
+		name string
+		fn   func()
+	}{
+		{"Enqueue/Dequeue", func() {
+			var id uint64
+			var price float64
+			var qty uint32
+			rb.Enqueue(1, 100.0, 1)
+			rb.Dequeue(&id, &price, &qty)
+		}},
+		{"EnqueueOrder/DequeueOrder", func() {
+			rb.EnqueueOrder(Order{ID: 1, Price: 100.0, Qty: 1})
+			rb.DequeueOrder()
+		}},
+		{"EnqueueOrder/DequeueInto", func() {
+			var o Order
+			rb.EnqueueOrder(Order{ID: 1, Price: 100.0, Qty: 1})
+			rb.DequeueInto(&o)
+		}},
+	}
+	for _, r := range rounds {
+		b.Run(r.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				r.fn()
+			}
+		})
+	}
+}
@@ -2,138 +2,246 @@ package main
 
 import (
 	"runtime"
+	"sync"
 	"sync/atomic"
 )
 
-const (
-	CacheLineSize = 64
-)
+const parallelInitThreshold = 1 << 20
 
-type RingBuffer struct {
-	capacity uint64
-	mask     uint64
-	_        [CacheLineSize]byte
+func Newbuffer(capacity uint64) *RingBuffer {
+	return NewbufferOpts(capacity)
+}
 
-	writeIndex uint64
-	_          [CacheLineSize - 8]byte
+// NewbufferWithWaitStrategy is Newbuffer with the strategy the blocking
+// operations use between retries.
+func NewbufferWithWaitStrategy(capacity uint64, ws WaitStrategy) *RingBuffer {
+	return NewbufferOpts(capacity, WithWaitStrategy(ws))
+}
 
-	readIndex uint64
-	_         [CacheLineSize - 8]byte
+// NewbufferOpts returns a buffer of the given capacity, which must be a power
+// of two and at least 2, configured by opts. Without options it matches Newbuffer.
+func NewbufferOpts(capacity uint64, opts ...Option) *RingBuffer {
+	checkCapacity("NewbufferOpts", capacity)
 
-	cycleState []uint64
-	ids        []uint64
-	prices     []float64
-	qtys       []uint32
-}
+	cfg := bufferConfig{wait: Yield, spinWait: DefaultSpinWait, policy: PolicyReject, batchSize: defaultBatchBuffers}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.batchSize < 1 {
+		panic("ring: batch buffer length must be positive")
+	}
 
-func Newbuffer(capacity uint64) *RingBuffer {
+	backing := allocBacking(cfg.backing, capacity)
 	buffer := &RingBuffer{
 		capacity:   capacity,
 		mask:       capacity - 1,
 		writeIndex: 0,
 		readIndex:  0,
-		cycleState: make([]uint64, capacity),
-		ids:        make([]uint64, capacity),
-		prices:     make([]float64, capacity),
-		qtys:       make([]uint32, capacity),
+		cycleState: backing.CycleState,
+		ids:        backing.IDs,
+		prices:     backing.Prices,
+		qtys:       backing.Qtys,
+		wait:       cfg.wait,
+		spinWait:   cfg.spinWait,
+		policy:     cfg.policy,
+		retryLimit: cfg.retryLimit,
+		scratch:    newScratchPool(cfg.batchSize, capacity),
+		done:       newDoneSignal(),
+		batchDedup: cfg.batchDedup,
+		oplog:      newOperationLog(cfg.opLogSize),
+		backing:    cfg.backing,
+		spill:      newSpillover(cfg.spillLimit),
+	}
+	if cfg.metrics {
+		buffer.stats = &bufferStats{contention: cfg.contention}
 	}
 
-	for i := uint64(0); i < capacity; i++ {
-		buffer.cycleState[i] = i
+	initCycleState(buffer.cycleState)
+	if buffer.spill != nil {
+		go buffer.spill.run(buffer)
 	}
 
+	if cfg.selfTest != nil {
+		cfg.selfTest.report(runSelfTest(capacity, cfg))
+	}
 	return buffer
 }
 
+// validCapacity reports whether the cycle-state scheme works with capacity
+// slots: a power of two, so that a sequence maps to its slot with a mask, no
+// larger than the cycle type can track, and at least 2. With a single slot
+// the stamp a consumer leaves when it releases sequence s, s+capacity, is the
+// stamp a producer leaves when it publishes s, s+1, so a full slot would look
+// free to the next producer.
+func validCapacity(capacity uint64) bool {
+	return capacity >= 2 && capacity&(capacity-1) == 0 && capacity <= maxCapacity
+}
+
+// checkCapacity panics with a *BufferError wrapping ErrInvalidCapacity unless
+// validCapacity accepts capacity.
+func checkCapacity(op string, capacity uint64) {
+	if !validCapacity(capacity) {
+		panic(&BufferError{Op: op, Capacity: capacity, Attempted: capacity, Err: ErrInvalidCapacity})
+	}
+}
+
+// initCycleState seeds slot i with sequence i, marking it free for the first
+// lap. This has to happen before the buffer is shared: a zeroed slot reads as
+// "still owned by lap -1" to every producer except the one at sequence 0, and
+// there is no way to tell a never-touched slot from one that is legitimately
+// waiting on a consumer, so the state cannot be established lazily on first
+// access. For large buffers the cost is dominated by faulting in the pages,
+// so the work is split across GOMAXPROCS goroutines instead.
+func initCycleState(cycleState []cycle) {
+	n := uint64(len(cycleState))
+	workers := uint64(runtime.GOMAXPROCS(0))
+	if n < parallelInitThreshold || workers < 2 {
+		for i := uint64(0); i < n; i++ {
+			cycleState[i] = cycle(i)
+		}
+		return
+	}
+
+	chunk := (n + workers - 1) / workers
+	var wg sync.WaitGroup
+	for start := uint64(0); start < n; start += chunk {
+		end := min(start+chunk, n)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				cycleState[i] = cycle(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 func (rb *RingBuffer) Enqueue(id uint64, price float64, qty uint32) bool {
+	if rb.Closed() {
+		return false
+	}
+	if rb.spill != nil {
+		return rb.spill.enqueue(rb, id, price, qty)
+	}
+	return rb.enqueue(id, price, qty)
+}
+
+// enqueue is Enqueue on an open buffer without spillover.
+func (rb *RingBuffer) enqueue(id uint64, price float64, qty uint32) bool {
 	var head uint64
 	var offset uint64
-	var cycleVal uint64
+	var cycleVal cycle
 	var diff int64
 
 	for {
 		head = atomic.LoadUint64(&rb.writeIndex)
 		offset = head & rb.mask
-		cycleVal = atomic.LoadUint64(&rb.cycleState[offset])
+		cycleVal = loadCycle(&rb.cycleState[offset])
 		
-		diff = int64(cycleVal) - int64(head)
+		diff = cycleDiff(cycleVal, head)
 
 		if diff == 0 {
 			if atomic.CompareAndSwapUint64(&rb.writeIndex, head, head+1) {
 				break
 			}
+			rb.stats.addWriteCASFailure()
 		} else if diff < 0 {
-			return false
+			if rb.policy != PolicyOverwrite {
+				rb.stats.addFull()
+				return false
+			}
+			rb.dropOldest(head)
 		}
 	}
 
 	rb.ids[offset] = id
 	rb.prices[offset] = price
 	rb.qtys[offset] = qty
-	atomic.StoreUint64(&rb.cycleState[offset], head+1)
+	storeCycle(&rb.cycleState[offset], head+1)
+	rb.countEnqueued(1)
+	rb.oplog.record(OpEnqueue, head, id, price, qty)
 	return true
 }
 
+// EnqueueBatch enqueues all of the batch or none of it and returns how many
+// items were written. Every slot in the range is checked before the claim:
+// consumers release slots out of order, so a free head and tail say nothing
+// about the slots between them.
 func (rb *RingBuffer) EnqueueBatch(ids []uint64, prices []float64, qtys []uint32) uint64 {
 	count := uint64(len(ids))
-	var head uint64
-	var offset uint64
-	var cycleVal uint64
-	var diff int64
+	if count == 0 || rb.Closed() {
+		return 0
+	}
+	if rb.batchDedup {
+		count = distinctRuns(ids)
+	}
+	if count > rb.capacity {
+		panic(rb.newError("EnqueueBatch", count, ErrBatchTooLarge))
+	}
 
 	for {
-		head = atomic.LoadUint64(&rb.writeIndex)
-		
-		offset = head & rb.mask
-		cycleVal = atomic.LoadUint64(&rb.cycleState[offset])
-		diff = int64(cycleVal) - int64(head)
-		
-		if diff < 0 {
-			return 0 
+		head := atomic.LoadUint64(&rb.writeIndex)
+		ok, stale := rb.canClaim(head, count)
+		if stale {
+			rb.stats.addEnqueueRetry()
+			continue
 		}
-		if diff == 0 {
-			tailOffset := (head + count - 1) & rb.mask
-			tailCycle := atomic.LoadUint64(&rb.cycleState[tailOffset])
-			
-			if int64(tailCycle) - int64(head + count - 1) < 0 {
-				return 0 
-			}
-			if atomic.CompareAndSwapUint64(&rb.writeIndex, head, head+count) {
-				for i := uint64(0); i < count; i++ {
-					idx := (head + i) & rb.mask
-					
-					rb.ids[idx] = ids[i]
-					rb.prices[idx] = prices[i]
-					rb.qtys[idx] = qtys[i]
-					atomic.StoreUint64(&rb.cycleState[idx], head + i + 1)
-				}
-				return count
+		if !ok {
+			rb.stats.addFull()
+			return 0
+		}
+
+		if atomic.CompareAndSwapUint64(&rb.writeIndex, head, head+count) {
+			if count < uint64(len(ids)) {
+				rb.copyInDistinct(head, ids, prices, qtys)
+			} else {
+				rb.copyIn(head, ids, prices, qtys)
 			}
+			rb.countEnqueued(count)
+			return count
 		}
+		rb.stats.addWriteCASFailure()
 	}
 }
 
+// DequeueBatch dequeues exactly len(ids) items or none and returns how many
+// it took. Two things can hold up one consumer while the buffer as a whole
+// keeps moving. First, the claim is a CAS on readIndex, and a consumer can
+// lose it any number of times in a row to faster peers. WithRetryLimit bounds
+// that by making the call give up and return 0 after so many lost races, so
+// the caller backs off with its own strategy; DequeueBatchFair removes it
+// altogether. Second, the claim only checks the first and last slot, so the
+// consumer may then wait on a slot in between that a stalled producer has
+// claimed but not published. That wait uses the buffer's spin-wait strategy,
+// which sleeps rather than spins once it drags on; DequeueBatchNoWait never
+// waits.
 func (rb *RingBuffer) DequeueBatch(ids []uint64, prices []float64, qtys []uint32) uint64 {
 	var tail uint64
 	var offset uint64
-	var cycleVal uint64
+	var cycleVal cycle
 	
 	limit := uint64(len(ids))
 	if limit == 0 { return 0 }
+	if limit > rb.capacity {
+		panic(rb.newError("DequeueBatch", limit, ErrBatchTooLarge))
+	}
 
-	for {
+	for lost := 1; ; lost++ {
 		tail = atomic.LoadUint64(&rb.readIndex)
 		
 		offset = tail & rb.mask
-		cycleVal = atomic.LoadUint64(&rb.cycleState[offset])
+		cycleVal = loadCycle(&rb.cycleState[offset])
 
-		if int64(cycleVal) - int64(tail+1) < 0 {
+		if cycleDiff(cycleVal, tail+1) < 0 {
+			rb.stats.addEmpty()
 			return 0 
 		}
 
 		tailOffset := (tail + limit - 1) & rb.mask
-		tailCycle := atomic.LoadUint64(&rb.cycleState[tailOffset])
-		if int64(tailCycle) - int64(tail + limit) < 0 {
+		tailCycle := loadCycle(&rb.cycleState[tailOffset])
+		if cycleDiff(tailCycle, tail + limit) < 0 {
+			rb.stats.addEmpty()
 			return 0 
 		}
 
@@ -142,41 +250,49 @@ func (rb *RingBuffer) DequeueBatch(ids []uint64, prices []float64, qtys []uint32
 				currIndex := tail + i
 				currOffset := currIndex & rb.mask
 				
-				for {
-					c := atomic.LoadUint64(&rb.cycleState[currOffset])
-					if c == currIndex + 1 { break }
-					runtime.Gosched()
+				for iter := 0; ; iter++ {
+					c := loadCycle(&rb.cycleState[currOffset])
+					if cycleDiff(c, currIndex + 1) == 0 { break }
+					rb.spinWait.Wait(iter)
 				}
 
 				ids[i]    = rb.ids[currOffset]
 				prices[i] = rb.prices[currOffset]
 				qtys[i]   = rb.qtys[currOffset]
 				
-				atomic.StoreUint64(&rb.cycleState[currOffset], currIndex + rb.capacity)
+				storeCycle(&rb.cycleState[currOffset], currIndex + rb.capacity)
+				rb.oplog.record(OpDequeue, currIndex, ids[i], prices[i], qtys[i])
 			}
+			rb.stats.addDequeued(limit)
 			return limit
 		}
+		rb.stats.addReadCASFailure()
+		if rb.retryLimit > 0 && lost >= rb.retryLimit {
+			return 0
+		}
 	}
 }
 
 func (rb *RingBuffer) Dequeue(id *uint64, price *float64, qty *uint32) bool {
 	var tail uint64
 	var offset uint64
-	var cycleVal uint64
+	var cycleVal cycle
 	var diff int64
 
 	for {
 		tail = atomic.LoadUint64(&rb.readIndex)
 		offset = tail & rb.mask
-		cycleVal = atomic.LoadUint64(&rb.cycleState[offset])
+		cycleVal = loadCycle(&rb.cycleState[offset])
 
-		diff = int64(cycleVal) - int64(tail+1)
+		diff = cycleDiff(cycleVal, tail+1)
 
 		if diff == 0 {
 			if atomic.CompareAndSwapUint64(&rb.readIndex, tail, tail+1) {
 				break
 			}
+			rb.stats.addReadCASFailure()
 		} else if diff < 0 {
+			rb.stats.addEmpty()
 			return false
 		}
 	}
@@ -185,6 +301,476 @@ func (rb *RingBuffer) Dequeue(id *uint64, price *float64, qty *uint32) bool {
 	*price = rb.prices[offset]
 	*qty = rb.qtys[offset]
 
-	atomic.StoreUint64(&rb.cycleState[offset], tail+rb.capacity)
+	storeCycle(&rb.cycleState[offset], tail+rb.capacity)
+	rb.stats.addDequeued(1)
+	rb.oplog.record(OpDequeue, tail, *id, *price, *qty)
 	return true
-}
\ No newline at end of file
+}
+
+// EnqueueOrder enqueues o, returning false if the buffer is full.
+func (rb *RingBuffer) EnqueueOrder(o Order) bool {
+	return rb.Enqueue(o.ID, o.Price, o.Qty)
+}
+
+// DequeueOrder dequeues one order, returning false if the buffer is empty.
+func (rb *RingBuffer) DequeueOrder() (Order, bool) {
+	var o Order
+	ok := rb.Dequeue(&o.ID, &o.Price, &o.Qty)
+	return o, ok
+}
+
+// DequeueInto dequeues one order into *o, returning false and leaving *o
+// untouched if the buffer is empty. It is Dequeue with the three fields of a
+// caller-owned Order as destinations, so it allocates nothing.
+func (rb *RingBuffer) DequeueInto(o *Order) bool {
+	return rb.Dequeue(&o.ID, &o.Price, &o.Qty)
+}
+
+// ForEach calls fn for each published item from readIndex up to writeIndex
+// without consuming it, stopping early if fn returns false. Slots that have
+// been claimed by a producer but not yet published are skipped. Indices are
+// never modified. Under concurrent producers or consumers the walk is a racy,
+// best-effort view: items can be consumed or overwritten while it runs, and a
+// slot that changes while being read is skipped. Such an overlapping read is
+// still a data race to the race detector; see Peek.
+func (rb *RingBuffer) ForEach(fn func(seq uint64, o Order) bool) {
+	tail := atomic.LoadUint64(&rb.readIndex)
+	head := atomic.LoadUint64(&rb.writeIndex)
+
+	for seq := tail; seq < head; seq++ {
+		offset := seq & rb.mask
+		if cycleDiff(loadCycle(&rb.cycleState[offset]), seq+1) != 0 {
+			continue
+		}
+
+		o := Order{ID: rb.ids[offset], Price: rb.prices[offset], Qty: rb.qtys[offset]}
+		if cycleDiff(loadCycle(&rb.cycleState[offset]), seq+1) != 0 {
+			continue
+		}
+		if !fn(seq, o) {
+			return
+		}
+	}
+}
+
+// Unread pushes o back in front of the next item, so the following Dequeue
+// returns it. It steps readIndex back by one and restores o into the slot that
+// was just released. It fails if nothing has been dequeued yet or if a
+// producer has already reclaimed that slot for the next lap.
+//
+// Unread is only safe with a single consumer, and only while no producer can
+// be enqueuing into the slot being restored, i.e. the buffer is not within
+// one item of full.
+func (rb *RingBuffer) Unread(o Order) bool {
+	tail := atomic.LoadUint64(&rb.readIndex)
+	if tail == 0 {
+		return false
+	}
+
+	seq := tail - 1
+	offset := seq & rb.mask
+	if !casCycle(&rb.cycleState[offset], seq+rb.capacity, seq) {
+		return false
+	}
+
+	rb.ids[offset] = o.ID
+	rb.prices[offset] = o.Price
+	rb.qtys[offset] = o.Qty
+	storeCycle(&rb.cycleState[offset], seq+1)
+	atomic.StoreUint64(&rb.readIndex, seq)
+	return true
+}
+
+// Laps returns how many times producers have wrapped around the ring, i.e.
+// writeIndex / capacity. It is a cheap diagnostic for confirming that slots
+// are actually being reused under load.
+func (rb *RingBuffer) Laps() uint64 {
+	return atomic.LoadUint64(&rb.writeIndex) / rb.capacity
+}
+
+// DequeueBatchNoWait is DequeueBatch without the publication wait. Instead of
+// claiming the range first and then spinning on slots a slow producer has not
+// published yet, it checks that every slot in the range is already published
+// before the CAS on readIndex. A successful CAS proves nobody consumed those
+// slots in between, so the copy never waits; if any slot is still pending the
+// call returns 0 and the caller should retry later.
+func (rb *RingBuffer) DequeueBatchNoWait(ids []uint64, prices []float64, qtys []uint32) uint64 {
+	limit := uint64(len(ids))
+	if limit == 0 {
+		return 0
+	}
+	if limit > rb.capacity {
+		panic(rb.newError("DequeueBatchNoWait", limit, ErrBatchTooLarge))
+	}
+
+	for {
+		tail := atomic.LoadUint64(&rb.readIndex)
+		count, stale := rb.readable(tail, limit)
+		if stale {
+			rb.stats.addDequeueRetry()
+			continue
+		}
+		if count < limit {
+			rb.stats.addEmpty()
+			return 0
+		}
+
+		if atomic.CompareAndSwapUint64(&rb.readIndex, tail, tail+limit) {
+			rb.copyOut(tail, ids[:limit], prices, qtys)
+			rb.stats.addDequeued(limit)
+			return limit
+		}
+		rb.stats.addReadCASFailure()
+	}
+}
+
+// readable counts how many consecutive slots starting at sequence tail are
+// published and not yet consumed, up to limit. The run ends at the first slot
+// a producer has not published yet. If it ends instead at a slot that has
+// already been consumed, tail was stale by the time the slot was read and
+// stale is true: the caller must reload readIndex and try again rather than
+// report the buffer empty.
+func (rb *RingBuffer) readable(tail, limit uint64) (count uint64, stale bool) {
+	for i := uint64(0); i < limit; i++ {
+		seq := tail + i
+		if diff := cycleDiff(loadCycle(&rb.cycleState[seq&rb.mask]), seq+1); diff != 0 {
+			return i, diff > 0
+		}
+	}
+	return limit, false
+}
+
+// copyOut copies len(ids) items starting at sequence tail out of the buffer
+// and releases their slots. The caller must own the range and have seen every
+// slot in it published.
+func (rb *RingBuffer) copyOut(tail uint64, ids []uint64, prices []float64, qtys []uint32) {
+	for i := range ids {
+		seq := tail + uint64(i)
+		offset := seq & rb.mask
+
+		ids[i] = rb.ids[offset]
+		prices[i] = rb.prices[offset]
+		qtys[i] = rb.qtys[offset]
+		storeCycle(&rb.cycleState[offset], seq+rb.capacity)
+	}
+}
+
+// DequeueBatchMin dequeues between minItems and maxItems items, bounded by
+// len(ids), and returns how many it took. If fewer than minItems are ready it
+// takes none and returns 0, so consumers that only profit from large batches
+// never pay for tiny ones. Like DequeueBatchNoWait it never waits on
+// unpublished slots.
+func (rb *RingBuffer) DequeueBatchMin(minItems, maxItems int, ids []uint64, prices []float64, qtys []uint32) uint64 {
+	limit := min(uint64(len(ids)), rb.capacity)
+	if maxItems >= 0 {
+		limit = min(limit, uint64(maxItems))
+	}
+	floor := uint64(max(minItems, 1))
+	if floor > limit {
+		return 0
+	}
+
+	for {
+		tail := atomic.LoadUint64(&rb.readIndex)
+		count, stale := rb.readable(tail, limit)
+		if stale {
+			rb.stats.addDequeueRetry()
+			continue
+		}
+		if count < floor {
+			rb.stats.addEmpty()
+			return 0
+		}
+
+		if atomic.CompareAndSwapUint64(&rb.readIndex, tail, tail+count) {
+			rb.copyOut(tail, ids[:count], prices, qtys)
+			rb.stats.addDequeued(count)
+			return count
+		}
+		rb.stats.addReadCASFailure()
+	}
+}
+
+// DequeueBatchUpTo dequeues whatever is ready, up to len(ids) items, and
+// returns how many it took. Unlike DequeueBatch it doesn't insist on a full
+// batch, so the last few items of a stream are still delivered after the
+// producers stop.
+func (rb *RingBuffer) DequeueBatchUpTo(ids []uint64, prices []float64, qtys []uint32) uint64 {
+	return rb.DequeueBatchMin(1, len(ids), ids, prices, qtys)
+}
+
+// Compact removes every buffered item for which remove returns true, shifting
+// the survivors towards the head so FIFO order is preserved, and returns how
+// many items were removed. It rewrites slots and moves writeIndex back, so it
+// must only be called on a quiesced buffer: no producer or consumer may run
+// concurrently.
+func (rb *RingBuffer) Compact(remove func(Order) bool) uint64 {
+	tail := atomic.LoadUint64(&rb.readIndex)
+	head := atomic.LoadUint64(&rb.writeIndex)
+
+	w := tail
+	for r := tail; r < head; r++ {
+		src := r & rb.mask
+		o := Order{ID: rb.ids[src], Price: rb.prices[src], Qty: rb.qtys[src]}
+		if remove(o) {
+			continue
+		}
+
+		dst := w & rb.mask
+		rb.ids[dst] = o.ID
+		rb.prices[dst] = o.Price
+		rb.qtys[dst] = o.Qty
+		storeCycle(&rb.cycleState[dst], w+1)
+		w++
+	}
+
+	for seq := w; seq < head; seq++ {
+		storeCycle(&rb.cycleState[seq&rb.mask], seq)
+	}
+	atomic.StoreUint64(&rb.writeIndex, w)
+	return head - w
+}
+
+// canClaim reports whether all count slots starting at sequence head are
+// free for producers, which is what an all-or-nothing claim has to know before
+// it moves writeIndex. stale is as for claimable: when it is true the answer
+// says nothing about the buffer and the caller must reload writeIndex.
+func (rb *RingBuffer) canClaim(head, count uint64) (ok, stale bool) {
+	n, stale := rb.claimable(head, count)
+	return n == count, stale
+}
+
+// claimable counts how many consecutive slots starting at sequence head are
+// free for producers, up to limit. The run ends at the first slot a consumer
+// has not released from the previous lap. If it ends instead at a slot some
+// other producer has already claimed, head was stale and stale is true: the
+// caller must reload writeIndex and try again rather than report the buffer
+// full.
+func (rb *RingBuffer) claimable(head, limit uint64) (count uint64, stale bool) {
+	for i := uint64(0); i < limit; i++ {
+		seq := head + i
+		if diff := cycleDiff(loadCycle(&rb.cycleState[seq&rb.mask]), seq); diff != 0 {
+			return i, diff > 0
+		}
+	}
+	return limit, false
+}
+
+// EnqueueRepeat enqueues up to n copies of o with a single claim and returns
+// how many were written, which is less than n when the buffer doesn't have
+// room for all of them.
+func (rb *RingBuffer) EnqueueRepeat(o Order, n uint64) uint64 {
+	n = min(n, rb.capacity)
+	if rb.Closed() {
+		return 0
+	}
+
+	for {
+		head := atomic.LoadUint64(&rb.writeIndex)
+		count, stale := rb.claimable(head, n)
+		if stale {
+			rb.stats.addEnqueueRetry()
+			continue
+		}
+		if count == 0 {
+			rb.stats.addFull()
+			return 0
+		}
+
+		if atomic.CompareAndSwapUint64(&rb.writeIndex, head, head+count) {
+			for i := uint64(0); i < count; i++ {
+				offset := (head + i) & rb.mask
+				rb.ids[offset] = o.ID
+				rb.prices[offset] = o.Price
+				rb.qtys[offset] = o.Qty
+				storeCycle(&rb.cycleState[offset], head+i+1)
+			}
+			rb.countEnqueued(count)
+			return count
+		}
+		rb.stats.addWriteCASFailure()
+	}
+}
+
+// DequeueBatchFair claims the next len(ids) sequences with a single atomic
+// add on readIndex, like drawing a ticket, and then waits until each slot in
+// that range is published. Unlike the CAS in DequeueBatch the add can't fail,
+// so every consumer gets its own contiguous range in arrival order and no
+// consumer can be starved by repeatedly losing the race.
+//
+// The cost is latency: the ticket may run ahead of writeIndex, in which case
+// the call blocks until producers catch up, and it cannot return early with
+// nothing. Use it for consumers that would wait anyway, and only once enough
+// items will eventually be produced to fill every ticket drawn.
+//
+// Every consumer of a buffer should use DequeueBatchFair, not a mix of it
+// and the CAS-based dequeues. An outstanding ticket leaves readIndex ahead of
+// writeIndex: the other dequeues then see an empty buffer until producers
+// have filled every ticket, and Len, IsEmpty and the high-water mark read 0
+// meanwhile.
+func (rb *RingBuffer) DequeueBatchFair(ids []uint64, prices []float64, qtys []uint32) uint64 {
+	limit := uint64(len(ids))
+	if limit == 0 {
+		return 0
+	}
+	if limit > rb.capacity {
+		panic(rb.newError("DequeueBatchFair", limit, ErrBatchTooLarge))
+	}
+
+	tail := atomic.AddUint64(&rb.readIndex, limit) - limit
+	for i := uint64(0); i < limit; i++ {
+		seq := tail + i
+		offset := seq & rb.mask
+		for iter := 0; cycleDiff(loadCycle(&rb.cycleState[offset]), seq+1) != 0; iter++ {
+			rb.spinWait.Wait(iter)
+		}
+
+		ids[i] = rb.ids[offset]
+		prices[i] = rb.prices[offset]
+		qtys[i] = rb.qtys[offset]
+		storeCycle(&rb.cycleState[offset], seq+rb.capacity)
+	}
+	rb.stats.addDequeued(limit)
+	return limit
+}
+
+// EnqueueBlocking retries Enqueue until it succeeds, waiting between attempts
+// according to the buffer's WaitStrategy. It returns false if the buffer is
+// closed.
+func (rb *RingBuffer) EnqueueBlocking(id uint64, price float64, qty uint32) bool {
+	for attempt := 0; !rb.Enqueue(id, price, qty); attempt++ {
+		if rb.Closed() {
+			return false
+		}
+		rb.wait.Wait(attempt)
+	}
+	return true
+}
+
+// DequeueBlocking retries Dequeue until an item is available, waiting between
+// attempts according to the buffer's WaitStrategy. Once the buffer is closed
+// and drained it returns false, so a consumer can simply loop until then.
+func (rb *RingBuffer) DequeueBlocking() (Order, bool) {
+	var o Order
+	for attempt := 0; !rb.Dequeue(&o.ID, &o.Price, &o.Qty); attempt++ {
+		if rb.closedEmpty() {
+			return Order{}, false
+		}
+		rb.wait.Wait(attempt)
+	}
+	return o, true
+}
+
+// Close marks the end of the stream. Every enqueue fails from then on, while
+// consumers keep draining what is already in the buffer. Like closing a
+// channel, Close belongs after the producers are done: an enqueue racing with
+// it may still land after a consumer has decided the stream is over. Calling
+// Close more than once is harmless. Done signals when the closed buffer has
+// drained.
+//
+// The blocking and context-aware dequeues report the end of the stream only
+// once the closed buffer has drained in the sense of Done. A batch that a
+// consumer claimed before Close, and is still copying out while it waits for
+// a producer to publish a slot in the middle, is delivered to that consumer
+// in full; meanwhile the other consumers keep waiting instead of reporting
+// closed and empty.
+func (rb *RingBuffer) Close() {
+	atomic.StoreUint32(&rb.closed, 1)
+	rb.done.closeOnce.Do(func() { close(rb.done.closing) })
+}
+
+func (rb *RingBuffer) Closed() bool {
+	return atomic.LoadUint32(&rb.closed) != 0
+}
+
+// Status returns Len and Closed together, for a consumer loop that keeps
+// going while len > 0 || !closed. The closed flag is read first: once a
+// producer has finished and Close has been called, every item enqueued before
+// Close is counted in len, so the loop can't exit with items left behind.
+func (rb *RingBuffer) Status() (len uint64, closed bool) {
+	closed = rb.Closed()
+	return rb.Len(), closed
+}
+
+// closedEmpty reports whether the buffer is closed and drained, so no item
+// is pending and none is still being copied out by a consumer. The drain
+// check scans the cycle state, but only once the buffer is closed and its
+// indices have met.
+func (rb *RingBuffer) closedEmpty() bool {
+	return rb.Closed() && rb.IsEmpty() && rb.drained()
+}
+
+// Len returns the number of items claimed by producers and not yet claimed by
+// consumers, writeIndex - readIndex. Under concurrent producers and consumers
+// it is a racy snapshot that may be slightly stale by the time it returns, but
+// it is always clamped to [0, capacity].
+func (rb *RingBuffer) Len() uint64 {
+	tail := atomic.LoadUint64(&rb.readIndex)
+	head := atomic.LoadUint64(&rb.writeIndex)
+	if head <= tail {
+		return 0
+	}
+	return min(head-tail, rb.capacity)
+}
+
+func (rb *RingBuffer) Cap() uint64 {
+	return rb.capacity
+}
+
+// IsEmpty reports whether Len is 0, with the same snapshot caveats.
+func (rb *RingBuffer) IsEmpty() bool {
+	return rb.Len() == 0
+}
+
+// IsFull reports whether Len has reached capacity, with the same snapshot
+// caveats.
+func (rb *RingBuffer) IsFull() bool {
+	return rb.Len() == rb.capacity
+}
+
+// EnqueueBatchPartial writes as much of the batch as currently fits and
+// returns how many items were written, which may be anywhere from 0 to
+// len(ids). The written items are always a prefix of the batch, claimed with
+// a single CAS on writeIndex, so callers advance their slices by the returned
+// count and retry with the rest.
+func (rb *RingBuffer) EnqueueBatchPartial(ids []uint64, prices []float64, qtys []uint32) uint64 {
+	limit := min(uint64(len(ids)), rb.capacity)
+	if limit == 0 || rb.Closed() {
+		return 0
+	}
+
+	for {
+		head := atomic.LoadUint64(&rb.writeIndex)
+		count, stale := rb.claimable(head, limit)
+		if stale {
+			rb.stats.addEnqueueRetry()
+			continue
+		}
+		if count == 0 {
+			rb.stats.addFull()
+			return 0
+		}
+
+		if atomic.CompareAndSwapUint64(&rb.writeIndex, head, head+count) {
+			rb.copyIn(head, ids[:count], prices, qtys)
+			rb.countEnqueued(count)
+			return count
+		}
+		rb.stats.addWriteCASFailure()
+	}
+}
+
+// copyIn writes len(ids) items into the slots starting at sequence head and
+// publishes each one. The caller must own the range.
+func (rb *RingBuffer) copyIn(head uint64, ids []uint64, prices []float64, qtys []uint32) {
+	for i := range ids {
+		seq := head + uint64(i)
+		offset := seq & rb.mask
+
+		rb.ids[offset] = ids[i]
+		rb.prices[offset] = prices[i]
+		rb.qtys[offset] = qtys[i]
+		storeCycle(&rb.cycleState[offset], seq+1)
+		rb.oplog.record(OpEnqueue, seq, ids[i], prices[i], qtys[i])
+	}
+}
@@ -0,0 +1,589 @@
+package main
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestEnqueueBatchConcurrent runs four batch producers against four batch
+// consumers on a small buffer, so producers keep reading a writeIndex that
+// another producer has already moved past. Every ID must come out exactly
+// once.
+func TestEnqueueBatchConcurrent(t *testing.T) {
+	const (
+		producers = 4
+		consumers = 4
+		perWorker = 20_000
+		batch     = 4
+	)
+	rb := Newbuffer(64)
+	total := producers * perWorker
+
+	var pwg sync.WaitGroup
+	pwg.Add(producers)
+	for p := range producers {
+		go func() {
+			defer pwg.Done()
+			ids := make([]uint64, batch)
+			prices := make([]float64, batch)
+			qtys := make([]uint32, batch)
+			for next := uint64(p * perWorker); next < uint64((p+1)*perWorker); next += batch {
+				for i := range ids {
+					ids[i] = next + uint64(i)
+				}
+				for rb.EnqueueBatch(ids, prices, qtys) == 0 {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+
+	seen := make([][]uint64, consumers)
+	var remaining sync.WaitGroup
+	remaining.Add(total)
+	done := make(chan struct{})
+	var cwg sync.WaitGroup
+	cwg.Add(consumers)
+	for c := range consumers {
+		go func() {
+			defer cwg.Done()
+			ids := make([]uint64, batch)
+			prices := make([]float64, batch)
+			qtys := make([]uint32, batch)
+			for {
+				n := rb.DequeueBatchUpTo(ids, prices, qtys)
+				if n == 0 {
+					select {
+					case <-done:
+						return
+					default:
+					}
+					runtime.Gosched()
+					continue
+				}
+				seen[c] = append(seen[c], ids[:n]...)
+				remaining.Add(-int(n))
+			}
+		}()
+	}
+
+	pwg.Wait()
+	remaining.Wait()
+	close(done)
+	cwg.Wait()
+
+	count := make([]int, total)
+	for _, ids := range seen {
+		for _, id := range ids {
+			if id >= uint64(total) {
+				t.Fatalf("dequeued unknown ID %d", id)
+			}
+			count[id]++
+		}
+	}
+	for id, n := range count {
+		if n != 1 {
+			t.Fatalf("ID %d dequeued %d times", id, n)
+		}
+	}
+	if !rb.IsEmpty() {
+		t.Fatalf("Len() = %d after draining, want 0", rb.Len())
+	}
+}
+
+// TestEnqueueBatchEStaleHead checks that EnqueueBatchE only reports ErrFull
+// when the slots are really taken: with one batch buffered there is still
+// room for another, and a claim that starts at a stale head must retry.
+func TestEnqueueBatchEStaleHead(t *testing.T) {
+	rb := Newbuffer(8)
+	ids := make([]uint64, 4)
+	prices := make([]float64, 4)
+	qtys := make([]uint32, 4)
+
+	if err := rb.EnqueueBatchE(ids, prices, qtys); err != nil {
+		t.Fatalf("first EnqueueBatchE: %v", err)
+	}
+	if ok, stale := rb.canClaim(0, 4); ok || !stale {
+		t.Fatalf("canClaim(0, 4) = %v, %v after the slots were taken, want false, true", ok, stale)
+	}
+	if err := rb.EnqueueBatchE(ids, prices, qtys); err != nil {
+		t.Fatalf("second EnqueueBatchE: %v", err)
+	}
+	if err := rb.EnqueueBatchE(ids[:1], prices[:1], qtys[:1]); err != ErrFull {
+		t.Fatalf("EnqueueBatchE on a full buffer = %v, want ErrFull", err)
+	}
+}
+
+// TestEnqueueBatchStraddlesOccupiedSlot is the batch-straddle regression:
+// the first and last slots of the range are free for the next lap but a slot
+// between them is still held by a slow consumer. EnqueueBatch must refuse the
+// whole range rather than overwrite that slot.
+func TestEnqueueBatchStraddlesOccupiedSlot(t *testing.T) {
+	rb := Newbuffer(8)
+	for i := range uint64(8) {
+		rb.Enqueue(i, 0, 0)
+	}
+	var o Order
+	for range 4 {
+		rb.DequeueInto(&o)
+	}
+	// A consumer has claimed sequence 1 but not released it yet.
+	storeCycle(&rb.cycleState[1], 2)
+
+	ids := []uint64{100, 101, 102, 103}
+	prices := make([]float64, 4)
+	qtys := make([]uint32, 4)
+	if n := rb.EnqueueBatch(ids, prices, qtys); n != 0 {
+		t.Fatalf("EnqueueBatch over a held slot wrote %d items", n)
+	}
+	if rb.ids[1] != 1 {
+		t.Fatalf("held slot overwritten: id %d, want 1", rb.ids[1])
+	}
+
+	rb.ReleaseRead(1)
+	if n := rb.EnqueueBatch(ids, prices, qtys); n != 4 {
+		t.Fatalf("EnqueueBatch after the release wrote %d items, want 4", n)
+	}
+}
+
+// TestCanClaim checks canClaim and claimable against hand-built cycle states
+// of a capacity-8 buffer. A slot is free for sequence s when its stamp is s;
+// s-8+1 means the previous lap's item is still unconsumed.
+func TestCanClaim(t *testing.T) {
+	tests := []struct {
+		name        string
+		cycles      [8]uint64
+		head, count uint64
+		want        uint64
+		stale       bool
+	}{
+		{"fresh", [8]uint64{0, 1, 2, 3, 4, 5, 6, 7}, 0, 8, 8, false},
+		{"second lap", [8]uint64{8, 9, 10, 11, 12, 13, 14, 15}, 8, 8, 8, false},
+		{"wrap free", [8]uint64{8, 9, 2, 3, 4, 5, 6, 7}, 6, 4, 4, false},
+		{"wrap hole", [8]uint64{8, 2, 3, 4, 5, 6, 6, 7}, 6, 4, 3, false},
+		{"hole at head", [8]uint64{8, 9, 10, 11, 12, 13, 7, 15}, 14, 2, 0, false},
+		{"hole past the end", [8]uint64{8, 9, 3, 4, 5, 6, 6, 7}, 6, 2, 2, false},
+		{"head taken", [8]uint64{8, 9, 10, 11, 12, 13, 7, 8}, 6, 2, 0, true},
+		{"middle taken", [8]uint64{9, 9, 2, 3, 4, 5, 6, 7}, 6, 4, 2, true},
+		{"head consumed", [8]uint64{8, 9, 10, 11, 12, 13, 14, 15}, 0, 1, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rb := Newbuffer(8)
+			for i, c := range tt.cycles {
+				storeCycle(&rb.cycleState[i], c)
+			}
+
+			n, stale := rb.claimable(tt.head, tt.count)
+			if n != tt.want || stale != tt.stale {
+				t.Errorf("claimable(%d, %d) = %d, %v, want %d, %v", tt.head, tt.count, n, stale, tt.want, tt.stale)
+			}
+			ok, stale := rb.canClaim(tt.head, tt.count)
+			if ok != (tt.want == tt.count) || stale != tt.stale {
+				t.Errorf("canClaim(%d, %d) = %v, %v, want %v, %v", tt.head, tt.count, ok, stale, tt.want == tt.count, tt.stale)
+			}
+		})
+	}
+}
+
+// TestBatchLargerThanCapacity checks that batches that can never fit fail at
+// once with ErrBatchTooLarge instead of spinning.
+func TestBatchLargerThanCapacity(t *testing.T) {
+	const capacity = 1024
+	for _, size := range []int{capacity + 1, 2 * capacity} {
+		ids := make([]uint64, size)
+		prices := make([]float64, size)
+		qtys := make([]uint32, size)
+
+		calls := []struct {
+			name string
+			call func(rb *RingBuffer)
+		}{
+			{"DequeueBatch", func(rb *RingBuffer) { rb.DequeueBatch(ids, prices, qtys) }},
+			{"DequeueBatchNoWait", func(rb *RingBuffer) { rb.DequeueBatchNoWait(ids, prices, qtys) }},
+			{"EnqueueBatch", func(rb *RingBuffer) { rb.EnqueueBatch(ids, prices, qtys) }},
+		}
+		for _, c := range calls {
+			rb := Newbuffer(capacity)
+			if err := panicsWithin(func() { c.call(rb) }); !errors.Is(err, ErrBatchTooLarge) {
+				t.Errorf("%s(%d) on capacity %d: recovered %v, want ErrBatchTooLarge", c.name, size, capacity, err)
+			}
+		}
+
+		rb := Newbuffer(capacity)
+		if _, err := rb.DequeueBatchE(ids, prices, qtys); !errors.Is(err, ErrBatchTooLarge) {
+			t.Errorf("DequeueBatchE(%d) = %v, want ErrBatchTooLarge", size, err)
+		}
+		if err := rb.EnqueueBatchE(ids, prices, qtys); !errors.Is(err, ErrBatchTooLarge) {
+			t.Errorf("EnqueueBatchE(%d) = %v, want ErrBatchTooLarge", size, err)
+		}
+	}
+}
+
+// panicsWithin runs fn and returns what it panicked with as an error, or nil
+// if it returned normally. A call still running after five seconds counts as
+// a hang and fails with an error of its own.
+func panicsWithin(fn func()) error {
+	result := make(chan error, 1)
+	go func() {
+		defer func() {
+			err, _ := recover().(error)
+			result <- err
+		}()
+		fn()
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(5 * time.Second):
+		return errors.New("call did not return")
+	}
+}
+
+func TestInvalidCapacity(t *testing.T) {
+	constructors := []struct {
+		name string
+		make func(capacity uint64)
+	}{
+		{"Newbuffer", func(c uint64) { Newbuffer(c) }},
+		{"NewbufferG", func(c uint64) { NewbufferG[Order](c) }},
+		{"NewInterleavedBuffer", func(c uint64) { NewInterleavedBuffer(c) }},
+		{"NewMPSCBuffer", func(c uint64) { NewMPSCBuffer(c) }},
+		{"NewSPMCBuffer", func(c uint64) { NewSPMCBuffer(c) }},
+		{"NewSPSCBuffer", func(c uint64) { NewSPSCBuffer(c) }},
+		{"NewBroadcastBuffer", func(c uint64) { NewBroadcastBuffer(c) }},
+		{"NewByteRingBuffer", func(c uint64) { NewByteRingBuffer(c, 8) }},
+	}
+	for _, c := range constructors {
+		for _, capacity := range []uint64{0, 1, 3, 12, 1000, maxCapacity << 1} {
+			err := panicsWithin(func() { c.make(capacity) })
+			var be *BufferError
+			if !errors.Is(err, ErrInvalidCapacity) || !errors.As(err, &be) || be.Capacity != capacity {
+				t.Errorf("%s(%d): recovered %v, want a BufferError wrapping ErrInvalidCapacity", c.name, capacity, err)
+			}
+		}
+		if err := panicsWithin(func() { c.make(2) }); err != nil {
+			t.Errorf("%s(2): %v", c.name, err)
+		}
+	}
+}
+
+// TestCapacityTwo runs single items through the smallest buffer allowed, the
+// one where batch ranges wrap on every lap.
+func TestCapacityTwo(t *testing.T) {
+	rb := Newbuffer(2)
+	for lap := range uint64(4) {
+		if !rb.Enqueue(2*lap, 0, 0) || !rb.Enqueue(2*lap+1, 0, 0) {
+			t.Fatalf("lap %d: enqueue into an empty buffer failed", lap)
+		}
+		if rb.Enqueue(99, 0, 0) {
+			t.Fatalf("lap %d: enqueue into a full buffer succeeded", lap)
+		}
+		for i := range uint64(2) {
+			o, ok := rb.DequeueOrder()
+			if !ok || o.ID != 2*lap+i {
+				t.Fatalf("lap %d: got %d, %v, want %d", lap, o.ID, ok, 2*lap+i)
+			}
+		}
+		if _, ok := rb.DequeueOrder(); ok {
+			t.Fatalf("lap %d: dequeue from an empty buffer succeeded", lap)
+		}
+	}
+}
+
+func TestUnread(t *testing.T) {
+	rb := Newbuffer(4)
+	if rb.Unread(Order{ID: 1}) {
+		t.Fatal("Unread before any Dequeue succeeded")
+	}
+
+	rb.Enqueue(1, 1.5, 10)
+	rb.Enqueue(2, 2.5, 20)
+	first, _ := rb.DequeueOrder()
+	if !rb.Unread(first) {
+		t.Fatal("Unread of the item just dequeued failed")
+	}
+	if rb.Len() != 2 {
+		t.Fatalf("Len() = %d after Unread, want 2", rb.Len())
+	}
+	for _, want := range []Order{{1, 1.5, 10}, {2, 2.5, 20}} {
+		if o, ok := rb.DequeueOrder(); !ok || o != want {
+			t.Fatalf("DequeueOrder = %+v, %v, want %+v", o, ok, want)
+		}
+	}
+
+	// A consumer may put back something other than what it took, such as a
+	// partially processed order.
+	if !rb.Unread(Order{ID: 2, Price: 2.5, Qty: 5}) {
+		t.Fatal("second Unread failed")
+	}
+	if o, _ := rb.DequeueOrder(); o.Qty != 5 {
+		t.Fatalf("DequeueOrder after Unread = %+v, want Qty 5", o)
+	}
+}
+
+// TestUnreadReclaimedSlot checks that Unread refuses once a producer has
+// taken the released slot for the next lap.
+func TestUnreadReclaimedSlot(t *testing.T) {
+	rb := Newbuffer(2)
+	rb.Enqueue(1, 0, 0)
+	rb.Enqueue(2, 0, 0)
+	o, _ := rb.DequeueOrder()
+	rb.Enqueue(3, 0, 0)
+	if rb.Unread(o) {
+		t.Fatal("Unread into a slot the next lap already holds succeeded")
+	}
+	for _, want := range []uint64{2, 3} {
+		if got, ok := rb.DequeueOrder(); !ok || got.ID != want {
+			t.Fatalf("DequeueOrder = %d, %v, want %d", got.ID, ok, want)
+		}
+	}
+}
+
+func TestLaps(t *testing.T) {
+	const capacity = 8
+	rb := Newbuffer(capacity)
+	if rb.Laps() != 0 {
+		t.Fatalf("Laps() = %d on a new buffer", rb.Laps())
+	}
+	var o Order
+	for i := range uint64(3 * capacity) {
+		rb.Enqueue(i, 0, 0)
+		rb.DequeueInto(&o)
+		if want := (i + 1) / capacity; rb.Laps() != want {
+			t.Fatalf("after %d items: Laps() = %d, want %d", i+1, rb.Laps(), want)
+		}
+	}
+	if rb.Laps() != 3 {
+		t.Fatalf("Laps() = %d after 3x capacity, want 3", rb.Laps())
+	}
+}
+
+// TestDequeueBatchNoWaitStalledProducer stalls a producer between Claim and
+// Publish in the middle of the batch's range. DequeueBatchNoWait must return
+// 0 at once and leave the published items in place, where DequeueBatch would
+// claim the range and wait for the stalled slot.
+func TestDequeueBatchNoWaitStalledProducer(t *testing.T) {
+	rb := Newbuffer(8)
+	rb.Enqueue(0, 0, 0)
+	seq, ok := rb.Claim()
+	if !ok {
+		t.Fatal("Claim failed")
+	}
+	rb.Enqueue(2, 0, 0)
+	rb.Enqueue(3, 0, 0)
+
+	ids := make([]uint64, 4)
+	prices := make([]float64, 4)
+	qtys := make([]uint32, 4)
+	start := time.Now()
+	var n uint64
+	if err := panicsWithin(func() { n = rb.DequeueBatchNoWait(ids, prices, qtys) }); err != nil {
+		t.Fatalf("DequeueBatchNoWait with a stalled producer: %v", err)
+	}
+	if n != 0 {
+		t.Fatalf("DequeueBatchNoWait = %d over an unpublished slot, want 0", n)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("DequeueBatchNoWait took %v with a stalled producer", elapsed)
+	}
+	if rb.Len() != 4 {
+		t.Fatalf("Len() = %d after the refused batch, want 4", rb.Len())
+	}
+
+	rb.SetID(seq, 1)
+	rb.Publish(seq)
+	if n := rb.DequeueBatchNoWait(ids, prices, qtys); n != 4 {
+		t.Fatalf("DequeueBatchNoWait after Publish = %d, want 4", n)
+	}
+	for i, id := range ids {
+		if id != uint64(i) {
+			t.Fatalf("item %d has ID %d", i, id)
+		}
+	}
+}
+
+func TestCompact(t *testing.T) {
+	rb := Newbuffer(8)
+	// Start mid-ring so the survivors wrap around the end of the arrays.
+	for i := range uint64(5) {
+		rb.Enqueue(i, 0, 0)
+		rb.DequeueOrder()
+	}
+	for i := range uint64(8) {
+		rb.Enqueue(i, float64(i), uint32(i))
+	}
+
+	removed := rb.Compact(func(o Order) bool { return o.ID%3 == 0 })
+	if removed != 3 {
+		t.Fatalf("Compact removed %d items, want 3", removed)
+	}
+	if rb.Len() != 5 {
+		t.Fatalf("Len() = %d after Compact, want 5", rb.Len())
+	}
+
+	// The freed slots must be usable again, after the survivors.
+	for i := range uint64(3) {
+		if !rb.Enqueue(100+i, 0, 0) {
+			t.Fatalf("Enqueue into a freed slot %d failed", i)
+		}
+	}
+	if rb.Enqueue(200, 0, 0) {
+		t.Fatal("Enqueue succeeded past the capacity after Compact")
+	}
+	for _, want := range []uint64{1, 2, 4, 5, 7, 100, 101, 102} {
+		o, ok := rb.DequeueOrder()
+		if !ok || o.ID != want || (want < 100 && (o.Price != float64(want) || o.Qty != uint32(want))) {
+			t.Fatalf("DequeueOrder = %+v, %v, want ID %d", o, ok, want)
+		}
+	}
+}
+
+func TestCompactNothingRemoved(t *testing.T) {
+	rb := Newbuffer(4)
+	if n := rb.Compact(func(Order) bool { return true }); n != 0 {
+		t.Fatalf("Compact of an empty buffer removed %d", n)
+	}
+	rb.Enqueue(1, 0, 0)
+	rb.Enqueue(2, 0, 0)
+	if n := rb.Compact(func(Order) bool { return false }); n != 0 {
+		t.Fatalf("Compact keeping everything removed %d", n)
+	}
+	if n := rb.Compact(func(Order) bool { return true }); n != 2 || !rb.IsEmpty() {
+		t.Fatalf("Compact removing everything = %d, Len() = %d, want 2 and 0", n, rb.Len())
+	}
+}
+
+func TestEnqueueRepeat(t *testing.T) {
+	const copies = 1000
+	rb := Newbuffer(1024)
+	o := Order{ID: 42, Price: 99.5, Qty: 7}
+	if n := rb.EnqueueRepeat(o, copies); n != copies {
+		t.Fatalf("EnqueueRepeat = %d, want %d", n, copies)
+	}
+	for i := range copies {
+		got, ok := rb.DequeueOrder()
+		if !ok || got != o {
+			t.Fatalf("copy %d = %+v, %v, want %+v", i, got, ok, o)
+		}
+	}
+	if !rb.IsEmpty() {
+		t.Fatalf("Len() = %d after dequeuing every copy", rb.Len())
+	}
+}
+
+// TestEnqueueRepeatPartial checks that EnqueueRepeat writes what fits and
+// nothing on a full or closed buffer.
+func TestEnqueueRepeatPartial(t *testing.T) {
+	rb := Newbuffer(8)
+	rb.Enqueue(1, 0, 0)
+	if n := rb.EnqueueRepeat(Order{ID: 2}, 100); n != 7 {
+		t.Fatalf("EnqueueRepeat into 7 free slots = %d, want 7", n)
+	}
+	if n := rb.EnqueueRepeat(Order{ID: 3}, 1); n != 0 {
+		t.Fatalf("EnqueueRepeat into a full buffer = %d, want 0", n)
+	}
+	rb.DequeueOrder()
+	rb.Close()
+	if n := rb.EnqueueRepeat(Order{ID: 3}, 1); n != 0 {
+		t.Fatalf("EnqueueRepeat into a closed buffer = %d, want 0", n)
+	}
+}
+
+func TestDequeueBatchMin(t *testing.T) {
+	rb := Newbuffer(32)
+	ids := make([]uint64, 16)
+	prices := make([]float64, 16)
+	qtys := make([]uint32, 16)
+
+	for i := range uint64(3) {
+		rb.Enqueue(i, 0, 0)
+	}
+	if n := rb.DequeueBatchMin(4, 16, ids, prices, qtys); n != 0 {
+		t.Fatalf("DequeueBatchMin(4, 16) with 3 ready = %d, want 0", n)
+	}
+	if rb.Len() != 3 {
+		t.Fatalf("Len() = %d after the refused batch, want 3", rb.Len())
+	}
+
+	for i := range uint64(2) {
+		rb.Enqueue(3+i, 0, 0)
+	}
+	if n := rb.DequeueBatchMin(4, 16, ids, prices, qtys); n != 5 {
+		t.Fatalf("DequeueBatchMin(4, 16) with 5 ready = %d, want 5", n)
+	}
+	for i, id := range ids[:5] {
+		if id != uint64(i) {
+			t.Fatalf("item %d has ID %d", i, id)
+		}
+	}
+
+	// maxItems caps the batch below len(ids).
+	for i := range uint64(10) {
+		rb.Enqueue(i, 0, 0)
+	}
+	if n := rb.DequeueBatchMin(4, 6, ids, prices, qtys); n != 6 {
+		t.Fatalf("DequeueBatchMin(4, 6) with 10 ready = %d, want 6", n)
+	}
+	if n := rb.DequeueBatchMin(8, 4, ids, prices, qtys); n != 0 {
+		t.Fatalf("DequeueBatchMin with min above max = %d, want 0", n)
+	}
+}
+
+func TestEnqueueBatchPartial(t *testing.T) {
+	rb := Newbuffer(8)
+	for i := range uint64(5) {
+		rb.Enqueue(i, 0, 0)
+	}
+	ids := []uint64{5, 6, 7, 8, 9}
+	prices := []float64{5, 6, 7, 8, 9}
+	qtys := []uint32{5, 6, 7, 8, 9}
+
+	n := rb.EnqueueBatchPartial(ids, prices, qtys)
+	if n != 3 {
+		t.Fatalf("EnqueueBatchPartial with room for 3 = %d, want 3", n)
+	}
+	if m := rb.EnqueueBatchPartial(ids[n:], prices[n:], qtys[n:]); m != 0 {
+		t.Fatalf("EnqueueBatchPartial into a full buffer = %d, want 0", m)
+	}
+
+	var o Order
+	for range 2 {
+		rb.DequeueInto(&o)
+	}
+	if m := rb.EnqueueBatchPartial(ids[n:], prices[n:], qtys[n:]); m != 2 {
+		t.Fatalf("EnqueueBatchPartial of the rest = %d, want 2", m)
+	}
+	for want := uint64(2); want < 10; want++ {
+		if !rb.DequeueInto(&o) || o.ID != want {
+			t.Fatalf("DequeueInto = %+v, want ID %d", o, want)
+		}
+	}
+}
+
+// TestEnqueueBatchPartialStopsAtHeldSlot checks that the written prefix ends
+// at the first slot a consumer has not released yet, even if later slots are
+// free.
+func TestEnqueueBatchPartialStopsAtHeldSlot(t *testing.T) {
+	rb := Newbuffer(4)
+	for i := range uint64(4) {
+		rb.Enqueue(i, 0, 0)
+	}
+	seq, _ := rb.ClaimRead()
+	var o Order
+	rb.DequeueInto(&o)
+	rb.DequeueInto(&o)
+
+	// Sequence 0 is still held; 1 and 2 are free.
+	ids := make([]uint64, 3)
+	if n := rb.EnqueueBatchPartial(ids, make([]float64, 3), make([]uint32, 3)); n != 0 {
+		t.Fatalf("EnqueueBatchPartial with the head slot held = %d, want 0", n)
+	}
+	rb.ReleaseRead(seq)
+	if n := rb.EnqueueBatchPartial(ids, make([]float64, 3), make([]uint32, 3)); n != 3 {
+		t.Fatalf("EnqueueBatchPartial after the release = %d, want 3", n)
+	}
+}
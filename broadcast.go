@@ -0,0 +1,173 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// BroadcastBuffer is a multicast ring: every subscriber receives every item
+// enqueued after it subscribed, each at its own pace. Producers claim slots
+// with a CAS on writeIndex as in RingBuffer, but a slot is only free again
+// once every subscriber has read past it, so the producers are held back by
+// the slowest subscriber alone: Enqueue reports full when that subscriber is
+// a whole lap behind, however far ahead the others are. A subscriber that
+// stops reading without calling Close therefore stalls the producers for good.
+// With no subscribers at all Enqueue reports full too, since nothing would
+// ever read the item.
+type BroadcastBuffer struct {
+	capacity uint64
+	mask     uint64
+	_        [CacheLineSize]byte
+
+	writeIndex uint64
+	_          [CacheLineSize - 8]byte
+
+	// gate is a lower bound on every subscriber's cursor, cached so producers
+	// only walk the subscribers when they seem to have run out of room. It is
+	// noGate while there are no subscribers.
+	gate uint64
+	_    [CacheLineSize - 8]byte
+
+	mu   sync.Mutex
+	subs atomic.Pointer[[]*Subscriber]
+
+	cycleState []cycle
+	ids        []uint64
+	prices     []float64
+	qtys       []uint32
+}
+
+// noGate is so far from any writeIndex that a producer seeing it always
+// walks the subscribers.
+const noGate = 1 << 63
+
+// Subscriber is one reader of a BroadcastBuffer. Its methods must only be
+// called from one goroutine at a time.
+type Subscriber struct {
+	cursor uint64
+	_      [CacheLineSize - 8]byte
+
+	rb *BroadcastBuffer
+}
+
+// NewBroadcastBuffer returns a buffer of the given capacity, which must be a
+// power of two.
+func NewBroadcastBuffer(capacity uint64) *BroadcastBuffer {
+	checkCapacity("NewBroadcastBuffer", capacity)
+
+	buffer := &BroadcastBuffer{
+		capacity:   capacity,
+		mask:       capacity - 1,
+		gate:       noGate,
+		cycleState: make([]cycle, capacity),
+		ids:        make([]uint64, capacity),
+		prices:     make([]float64, capacity),
+		qtys:       make([]uint32, capacity),
+	}
+	buffer.subs.Store(new([]*Subscriber))
+	initCycleState(buffer.cycleState)
+	return buffer
+}
+
+// Subscribe registers a subscriber that receives every item enqueued from now
+// on. It is safe to call while producers run.
+func (rb *BroadcastBuffer) Subscribe() *Subscriber {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	// The cursor starts at 0, which holds producers back, and only moves to
+	// writeIndex once the subscriber is visible to them. A producer that read
+	// the cursors before that may still claim slots, but none it can reach
+	// is one the new subscriber will read.
+	s := &Subscriber{rb: rb}
+	subs := append(slices.Clone(*rb.subs.Load()), s)
+	rb.subs.Store(&subs)
+	atomic.StoreUint64(&s.cursor, atomic.LoadUint64(&rb.writeIndex))
+	return s
+}
+
+// Close unsubscribes s, so producers no longer wait for it. s must not be
+// used afterwards.
+func (s *Subscriber) Close() {
+	rb := s.rb
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	subs := slices.DeleteFunc(slices.Clone(*rb.subs.Load()), func(o *Subscriber) bool { return o == s })
+	rb.subs.Store(&subs)
+	if len(subs) == 0 {
+		atomic.StoreUint64(&rb.gate, noGate)
+	}
+}
+
+func (rb *BroadcastBuffer) Enqueue(id uint64, price float64, qty uint32) bool {
+	var head uint64
+	var offset uint64
+
+	for {
+		head = atomic.LoadUint64(&rb.writeIndex)
+		if head-atomic.LoadUint64(&rb.gate) >= rb.capacity {
+			gate, ok := rb.refreshGate()
+			if !ok || head-gate >= rb.capacity {
+				return false
+			}
+		}
+
+		if atomic.CompareAndSwapUint64(&rb.writeIndex, head, head+1) {
+			break
+		}
+	}
+
+	offset = head & rb.mask
+	rb.ids[offset] = id
+	rb.prices[offset] = price
+	rb.qtys[offset] = qty
+	storeCycle(&rb.cycleState[offset], head+1)
+	return true
+}
+
+// refreshGate recomputes gate from the subscribers' cursors. The list is
+// loaded again afterwards and the walk repeated if a subscriber joined
+// meanwhile, so the bound never includes cursors read after a new subscriber
+// became visible; see Subscribe. It returns false if there are no
+// subscribers.
+func (rb *BroadcastBuffer) refreshGate() (uint64, bool) {
+	for {
+		subs := rb.subs.Load()
+		if len(*subs) == 0 {
+			return 0, false
+		}
+
+		gate := ^uint64(0)
+		for _, s := range *subs {
+			gate = min(gate, atomic.LoadUint64(&s.cursor))
+		}
+		if rb.subs.Load() == subs {
+			atomic.StoreUint64(&rb.gate, gate)
+			return gate, true
+		}
+	}
+}
+
+// Dequeue copies the subscriber's next item out, or returns false if it has
+// read everything published so far.
+func (s *Subscriber) Dequeue(id *uint64, price *float64, qty *uint32) bool {
+	rb := s.rb
+	seq := s.cursor
+	offset := seq & rb.mask
+	if cycleDiff(loadCycle(&rb.cycleState[offset]), seq+1) != 0 {
+		return false
+	}
+
+	*id = rb.ids[offset]
+	*price = rb.prices[offset]
+	*qty = rb.qtys[offset]
+	atomic.StoreUint64(&s.cursor, seq+1)
+	return true
+}
+
+// Lag returns how many published or claimed items s has yet to read.
+func (s *Subscriber) Lag() uint64 {
+	return atomic.LoadUint64(&s.rb.writeIndex) - atomic.LoadUint64(&s.cursor)
+}
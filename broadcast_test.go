@@ -0,0 +1,97 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestBroadcastFastAndSlow runs a fast and a slow subscriber against one
+// producer over many laps of a small ring: both must receive the full stream
+// in order, the slow one holding the producer back rather than missing items.
+func TestBroadcastFastAndSlow(t *testing.T) {
+	const total = 5_000
+	rb := NewBroadcastBuffer(8)
+	subs := []*Subscriber{rb.Subscribe(), rb.Subscribe()}
+
+	var wg sync.WaitGroup
+	wg.Add(len(subs))
+	for i, s := range subs {
+		go func() {
+			defer wg.Done()
+			var id uint64
+			var price float64
+			var qty uint32
+			for want := uint64(0); want < total; {
+				if !s.Dequeue(&id, &price, &qty) {
+					runtime.Gosched()
+					continue
+				}
+				if id != want || price != float64(want) {
+					t.Errorf("subscriber %d: got %d, %v, want %d", i, id, price, want)
+					return
+				}
+				want++
+				if i == 1 && want%64 == 0 {
+					time.Sleep(50 * time.Microsecond)
+				}
+			}
+		}()
+	}
+
+	for i := range uint64(total) {
+		for !rb.Enqueue(i, float64(i), 0) {
+			runtime.Gosched()
+		}
+	}
+	wg.Wait()
+	for i, s := range subs {
+		if s.Lag() != 0 {
+			t.Errorf("subscriber %d: Lag() = %d after the stream, want 0", i, s.Lag())
+		}
+	}
+}
+
+// TestBroadcastBackpressure checks that the slowest subscriber alone decides
+// when Enqueue reports full, and that closing it releases the producer.
+func TestBroadcastBackpressure(t *testing.T) {
+	rb := NewBroadcastBuffer(4)
+	if rb.Enqueue(0, 0, 0) {
+		t.Fatal("Enqueue with no subscribers succeeded")
+	}
+
+	fast, slow := rb.Subscribe(), rb.Subscribe()
+	var id uint64
+	var price float64
+	var qty uint32
+	for i := range uint64(4) {
+		if !rb.Enqueue(i, 0, 0) {
+			t.Fatalf("Enqueue(%d) failed with room", i)
+		}
+		fast.Dequeue(&id, &price, &qty)
+	}
+	if rb.Enqueue(4, 0, 0) {
+		t.Fatal("Enqueue succeeded with the slow subscriber a full lap behind")
+	}
+	if slow.Lag() != 4 || fast.Lag() != 0 {
+		t.Fatalf("Lag() = %d slow, %d fast, want 4 and 0", slow.Lag(), fast.Lag())
+	}
+
+	slow.Dequeue(&id, &price, &qty)
+	if id != 0 || !rb.Enqueue(4, 0, 0) {
+		t.Fatalf("slow subscriber read %d; Enqueue after it moved on must succeed", id)
+	}
+	if rb.Enqueue(5, 0, 0) {
+		t.Fatal("Enqueue succeeded with the slow subscriber a full lap behind again")
+	}
+	slow.Close()
+	if !rb.Enqueue(5, 0, 0) {
+		t.Fatal("Enqueue failed after the slow subscriber closed")
+	}
+
+	late := rb.Subscribe()
+	if late.Dequeue(&id, &price, &qty) {
+		t.Fatalf("a new subscriber received item %d enqueued before it subscribed", id)
+	}
+}
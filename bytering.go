@@ -0,0 +1,112 @@
+package main
+
+import "sync/atomic"
+
+// ByteRingBuffer is the lock-free MPMC ring carrying fixed-width byte records
+// instead of Order columns, for payloads such as serialized frames that don't
+// fit the id/price/qty shape. Every slot is width bytes of one contiguous
+// array, and records are copied in and out, so callers keep ownership of
+// their slices. Sequencing is the same per-slot cycle state as RingBuffer.
+type ByteRingBuffer struct {
+	capacity uint64
+	mask     uint64
+	width    uint64
+	_        [CacheLineSize]byte
+
+	writeIndex uint64
+	_          [CacheLineSize - 8]byte
+
+	readIndex uint64
+	_         [CacheLineSize - 8]byte
+
+	cycleState []cycle
+	data       []byte
+}
+
+// NewByteRingBuffer returns a buffer of capacity records of width bytes each.
+// capacity must be a power of two.
+func NewByteRingBuffer(capacity, width uint64) *ByteRingBuffer {
+	checkCapacity("NewByteRingBuffer", capacity)
+	if width == 0 {
+		panic("ring: record width must be positive")
+	}
+
+	buffer := &ByteRingBuffer{
+		capacity:   capacity,
+		mask:       capacity - 1,
+		width:      width,
+		cycleState: make([]cycle, capacity),
+		data:       make([]byte, capacity*width),
+	}
+	initCycleState(buffer.cycleState)
+	return buffer
+}
+
+// Width returns the record size the buffer was built with.
+func (rb *ByteRingBuffer) Width() int {
+	return int(rb.width)
+}
+
+// Enqueue copies record into the next free slot. It returns false if the
+// buffer is full or len(record) is not the buffer's width.
+func (rb *ByteRingBuffer) Enqueue(record []byte) bool {
+	if uint64(len(record)) != rb.width {
+		return false
+	}
+
+	var head uint64
+	var offset uint64
+
+	for {
+		head = atomic.LoadUint64(&rb.writeIndex)
+		offset = head & rb.mask
+		diff := cycleDiff(loadCycle(&rb.cycleState[offset]), head)
+
+		if diff == 0 {
+			if atomic.CompareAndSwapUint64(&rb.writeIndex, head, head+1) {
+				break
+			}
+		} else if diff < 0 {
+			return false
+		}
+	}
+
+	copy(rb.slot(offset), record)
+	storeCycle(&rb.cycleState[offset], head+1)
+	return true
+}
+
+// Dequeue copies the oldest record into dst. It returns false if the buffer
+// is empty or dst is shorter than the buffer's width; bytes of dst past the
+// width are left alone.
+func (rb *ByteRingBuffer) Dequeue(dst []byte) bool {
+	if uint64(len(dst)) < rb.width {
+		return false
+	}
+
+	var tail uint64
+	var offset uint64
+
+	for {
+		tail = atomic.LoadUint64(&rb.readIndex)
+		offset = tail & rb.mask
+		diff := cycleDiff(loadCycle(&rb.cycleState[offset]), tail+1)
+
+		if diff == 0 {
+			if atomic.CompareAndSwapUint64(&rb.readIndex, tail, tail+1) {
+				break
+			}
+		} else if diff < 0 {
+			return false
+		}
+	}
+
+	copy(dst, rb.slot(offset))
+	storeCycle(&rb.cycleState[offset], tail+rb.capacity)
+	return true
+}
+
+func (rb *ByteRingBuffer) slot(offset uint64) []byte {
+	start := offset * rb.width
+	return rb.data[start : start+rb.width : start+rb.width]
+}
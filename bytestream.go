@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"sync/atomic"
+)
+
+const recordHeader = 4
+
+// ByteStream is a single-producer / single-consumer byte ring carrying
+// variable-length records. Each record is stored as a 4-byte little-endian
+// length followed by its bytes. Records that cross the end of the ring are
+// split and reassembled transparently, so no space is wasted on padding at
+// the wrap point.
+//
+// Append must only be called from one goroutine and Read from one goroutine;
+// they may run concurrently with each other.
+type ByteStream struct {
+	size uint64
+	mask uint64
+	_    [CacheLineSize]byte
+
+	writeIndex uint64
+	_          [CacheLineSize - 8]byte
+
+	readIndex uint64
+	_         [CacheLineSize - 8]byte
+
+	data []byte
+}
+
+// NewByteStream returns a stream holding size bytes, headers included. size
+// must be a power of two.
+func NewByteStream(size uint64) *ByteStream {
+	checkCapacity("NewByteStream", size)
+
+	return &ByteStream{
+		size: size,
+		mask: size - 1,
+		data: make([]byte, size),
+	}
+}
+
+// Append copies rec into the stream. It returns false if there is not enough
+// free space right now, or if the record can never fit: it is longer than the
+// stream minus its header, or than the header's 32-bit length can describe.
+func (s *ByteStream) Append(rec []byte) bool {
+	if !s.fits(rec) {
+		return false
+	}
+
+	need := uint64(recordHeader + len(rec))
+	head := s.writeIndex
+	tail := atomic.LoadUint64(&s.readIndex)
+	if head+need-tail > s.size {
+		return false
+	}
+
+	var hdr [recordHeader]byte
+	binary.LittleEndian.PutUint32(hdr[:], uint32(len(rec)))
+	s.copyIn(head, hdr[:])
+	s.copyIn(head+recordHeader, rec)
+
+	atomic.StoreUint64(&s.writeIndex, head+need)
+	return true
+}
+
+// AppendE is Append reporting why it failed: a *BufferError wrapping
+// ErrRecordTooLarge for a record that can never fit, or ErrFull if there is
+// not enough free space right now.
+func (s *ByteStream) AppendE(rec []byte) error {
+	if !s.fits(rec) {
+		return &BufferError{
+			Op:         "AppendE",
+			ReadIndex:  atomic.LoadUint64(&s.readIndex),
+			WriteIndex: atomic.LoadUint64(&s.writeIndex),
+			Capacity:   s.size,
+			Attempted:  uint64(len(rec)),
+			Err:        ErrRecordTooLarge,
+		}
+	}
+	if !s.Append(rec) {
+		return ErrFull
+	}
+	return nil
+}
+
+// fits reports whether rec fits in an empty stream with its header, and its
+// length in the header.
+func (s *ByteStream) fits(rec []byte) bool {
+	n := uint64(len(rec))
+	return s.size >= recordHeader && n <= s.size-recordHeader && n <= math.MaxUint32
+}
+
+// Read returns the next record in a newly allocated slice, or false if the
+// stream is empty.
+func (s *ByteStream) Read() ([]byte, bool) {
+	tail := s.readIndex
+	head := atomic.LoadUint64(&s.writeIndex)
+	if tail == head {
+		return nil, false
+	}
+
+	var hdr [recordHeader]byte
+	s.copyOut(tail, hdr[:])
+	rec := make([]byte, binary.LittleEndian.Uint32(hdr[:]))
+	s.copyOut(tail+recordHeader, rec)
+
+	atomic.StoreUint64(&s.readIndex, tail+recordHeader+uint64(len(rec)))
+	return rec, true
+}
+
+func (s *ByteStream) copyIn(pos uint64, src []byte) {
+	n := copy(s.data[pos&s.mask:], src)
+	copy(s.data, src[n:])
+}
+
+func (s *ByteStream) copyOut(pos uint64, dst []byte) {
+	n := copy(dst, s.data[pos&s.mask:])
+	copy(dst[n:], s.data)
+}
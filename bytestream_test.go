@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestByteStreamWrap(t *testing.T) {
+	s := NewByteStream(32)
+	for i := range 20 {
+		rec := bytes.Repeat([]byte{byte(i)}, 1+i%9)
+		if err := s.AppendE(rec); err != nil {
+			t.Fatalf("AppendE %d: %v", i, err)
+		}
+		got, ok := s.Read()
+		if !ok || !bytes.Equal(got, rec) {
+			t.Fatalf("Read %d = %v, %v, want %v", i, got, ok, rec)
+		}
+	}
+}
+
+func TestByteStreamRecordTooLarge(t *testing.T) {
+	s := NewByteStream(16)
+	if err := s.AppendE(make([]byte, 12)); err != nil {
+		t.Fatalf("AppendE of size-4 bytes: %v", err)
+	}
+	if err := s.AppendE(make([]byte, 1)); err != ErrFull {
+		t.Fatalf("AppendE into a full stream = %v, want ErrFull", err)
+	}
+	s.Read()
+
+	err := s.AppendE(make([]byte, 13))
+	var be *BufferError
+	if !errors.Is(err, ErrRecordTooLarge) || !errors.As(err, &be) || be.Attempted != 13 {
+		t.Fatalf("AppendE of size-3 bytes = %v, want a BufferError wrapping ErrRecordTooLarge", err)
+	}
+	if s.Append(make([]byte, 13)) {
+		t.Fatal("Append of a record that can never fit succeeded")
+	}
+}
+
+func TestNewByteStreamInvalidSize(t *testing.T) {
+	for _, size := range []uint64{0, 1, 24, 100} {
+		if err := panicsWithin(func() { NewByteStream(size) }); !errors.Is(err, ErrInvalidCapacity) {
+			t.Errorf("NewByteStream(%d): recovered %v, want ErrInvalidCapacity", size, err)
+		}
+	}
+}
@@ -0,0 +1,6 @@
+package main
+
+// CacheLineSize is the padding unit between fields written by different
+// goroutines. Apple Silicon and many arm64 servers have 128-byte lines, and
+// where they don't, the extra padding only costs memory.
+const CacheLineSize = 128
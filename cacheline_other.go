@@ -0,0 +1,8 @@
+//go:build !arm64
+
+package main
+
+// CacheLineSize is the padding unit between fields written by different
+// goroutines. 64 bytes matches amd64 and most other targets; arm64 uses 128,
+// see cacheline_arm64.go.
+const CacheLineSize = 64
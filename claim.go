@@ -0,0 +1,93 @@
+package main
+
+import "sync/atomic"
+
+// Claim reserves the next write slot and returns its sequence, or false if the
+// buffer is full or closed. Together with the setters and Publish it lets a
+// producer write straight into the buffer's columns instead of passing values
+// through Enqueue:
+//
+//	seq, ok := rb.Claim()
+//	if ok {
+//		rb.SetID(seq, id)
+//		rb.SetPrice(seq, price)
+//		rb.SetQty(seq, qty)
+//		rb.Publish(seq)
+//	}
+//
+// ClaimRead, GetAt and ReleaseRead are the consumer side. This is an advanced
+// API with no safety net: a claimed sequence must be published or released
+// exactly once, consumers can't get past that slot until it is, and touching
+// a sequence you don't hold corrupts the buffer. Claim never overwrites, even
+// under PolicyOverwrite.
+func (rb *RingBuffer) Claim() (uint64, bool) {
+	if rb.Closed() {
+		return 0, false
+	}
+
+	for {
+		head := atomic.LoadUint64(&rb.writeIndex)
+		diff := cycleDiff(loadCycle(&rb.cycleState[head&rb.mask]), head)
+
+		if diff == 0 {
+			if atomic.CompareAndSwapUint64(&rb.writeIndex, head, head+1) {
+				rb.countEnqueued(1)
+				return head, true
+			}
+			rb.stats.addWriteCASFailure()
+		} else if diff < 0 {
+			rb.stats.addFull()
+			return 0, false
+		}
+	}
+}
+
+func (rb *RingBuffer) SetID(seq uint64, id uint64) {
+	rb.ids[seq&rb.mask] = id
+}
+
+func (rb *RingBuffer) SetPrice(seq uint64, price float64) {
+	rb.prices[seq&rb.mask] = price
+}
+
+func (rb *RingBuffer) SetQty(seq uint64, qty uint32) {
+	rb.qtys[seq&rb.mask] = qty
+}
+
+// Publish makes a claimed slot visible to consumers. The slot must not be
+// written after this.
+func (rb *RingBuffer) Publish(seq uint64) {
+	storeCycle(&rb.cycleState[seq&rb.mask], seq+1)
+}
+
+// ClaimRead reserves the oldest published slot and returns its sequence, or
+// false if nothing is ready.
+func (rb *RingBuffer) ClaimRead() (uint64, bool) {
+	for {
+		tail := atomic.LoadUint64(&rb.readIndex)
+		diff := cycleDiff(loadCycle(&rb.cycleState[tail&rb.mask]), tail+1)
+
+		if diff == 0 {
+			if atomic.CompareAndSwapUint64(&rb.readIndex, tail, tail+1) {
+				rb.stats.addDequeued(1)
+				return tail, true
+			}
+			rb.stats.addReadCASFailure()
+		} else if diff < 0 {
+			rb.stats.addEmpty()
+			return 0, false
+		}
+	}
+}
+
+// GetAt returns the item in a slot claimed with ClaimRead.
+func (rb *RingBuffer) GetAt(seq uint64) Order {
+	offset := seq & rb.mask
+	return Order{ID: rb.ids[offset], Price: rb.prices[offset], Qty: rb.qtys[offset]}
+}
+
+// ReleaseRead hands a slot claimed with ClaimRead back to producers. The slot
+// must not be read after this.
+func (rb *RingBuffer) ReleaseRead(seq uint64) {
+	storeCycle(&rb.cycleState[seq&rb.mask], seq+rb.capacity)
+}
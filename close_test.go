@@ -0,0 +1,93 @@
+package main
+
+import (
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestCloseDuringBatchDequeue closes the buffer while a consumer's
+// DequeueBatch has claimed a range whose middle slot a producer has not yet
+// published. The claimed items must all reach that consumer, and nothing may
+// report the stream as closed and empty until they have.
+func TestCloseDuringBatchDequeue(t *testing.T) {
+	rb := Newbuffer(8)
+	rb.Enqueue(0, 0, 0)
+	seq, ok := rb.Claim()
+	if !ok {
+		t.Fatal("Claim failed on an empty buffer")
+	}
+	rb.Enqueue(2, 0, 0)
+
+	ids := make([]uint64, 3)
+	batch := make(chan uint64, 1)
+	go func() { batch <- rb.DequeueBatch(ids, make([]float64, 3), make([]uint32, 3)) }()
+	for atomic.LoadUint64(&rb.readIndex) != 3 {
+		runtime.Gosched()
+	}
+
+	rb.Close()
+	done := rb.Done()
+	blocking := make(chan bool, 1)
+	go func() {
+		_, ok := rb.DequeueBlocking()
+		blocking <- ok
+	}()
+
+	select {
+	case <-blocking:
+		t.Fatal("DequeueBlocking reported the end of the stream with a batch still in flight")
+	case <-done:
+		t.Fatal("Done fired with a batch still in flight")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if _, err := rb.DequeueBatchE(ids[:1], make([]float64, 1), make([]uint32, 1)); err != nil {
+		t.Fatalf("DequeueBatchE with a batch in flight = %v, want nil", err)
+	}
+
+	rb.SetID(seq, 1)
+	rb.Publish(seq)
+	if n := <-batch; n != 3 || ids[0] != 0 || ids[1] != 1 || ids[2] != 2 {
+		t.Fatalf("in-flight DequeueBatch = %d, %v, want 3 items 0, 1, 2", n, ids)
+	}
+	select {
+	case ok := <-blocking:
+		if ok {
+			t.Fatal("DequeueBlocking returned an item after the drain")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("DequeueBlocking did not return after the drain")
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Done did not fire after the drain")
+	}
+}
+
+func TestStatus(t *testing.T) {
+	rb := Newbuffer(8)
+	if n, closed := rb.Status(); n != 0 || closed {
+		t.Fatalf("Status() on a new buffer = %d, %v, want 0, false", n, closed)
+	}
+	for i := range uint64(3) {
+		rb.Enqueue(i, 0, 0)
+	}
+	rb.Close()
+
+	var got []uint64
+	for n, closed := rb.Status(); n > 0 || !closed; n, closed = rb.Status() {
+		o, ok := rb.DequeueOrder()
+		if !ok {
+			t.Fatalf("Status() = %d, %v but Dequeue found nothing", n, closed)
+		}
+		got = append(got, o.ID)
+	}
+	if len(got) != 3 || got[0] != 0 || got[2] != 2 {
+		t.Fatalf("loop over Status consumed %v, want 0, 1, 2", got)
+	}
+	if n, closed := rb.Status(); n != 0 || !closed {
+		t.Fatalf("Status() after the drain = %d, %v, want 0, true", n, closed)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import "sync/atomic"
+
+// The ring package is itself a main package, so it cannot be imported here.
+// mpscBuffer and spmcBuffer repeat the index protocols of its MPSCBuffer and
+// SPMCBuffer: the same cycle stamps, the same CAS on the shared side and the
+// same plain load and store on the single-owner side. They leave out the
+// padding and the cycle32 variant, which change neither protocol. A change
+// to either protocol in mpsc.go or spmc.go belongs here too.
+
+// ring holds the columns and per-slot cycle stamps both buffers share. A slot
+// is free for sequence s when its stamp is s and published when it is s+1;
+// a release stamps s+capacity.
+type ring struct {
+	capacity   uint64
+	mask       uint64
+	writeIndex uint64
+	readIndex  uint64
+	cycleState []uint64
+	ids        []uint64
+	prices     []float64
+	qtys       []uint32
+}
+
+func newRing(capacity uint64) ring {
+	r := ring{
+		capacity:   capacity,
+		mask:       capacity - 1,
+		cycleState: make([]uint64, capacity),
+		ids:        make([]uint64, capacity),
+		prices:     make([]float64, capacity),
+		qtys:       make([]uint32, capacity),
+	}
+	for i := range r.cycleState {
+		r.cycleState[i] = uint64(i)
+	}
+	return r
+}
+
+func (r *ring) diff(offset, seq uint64) int64 {
+	return int64(atomic.LoadUint64(&r.cycleState[offset]) - seq)
+}
+
+func (r *ring) write(offset, head uint64, id uint64, price float64, qty uint32) {
+	r.ids[offset] = id
+	r.prices[offset] = price
+	r.qtys[offset] = qty
+	atomic.StoreUint64(&r.cycleState[offset], head+1)
+}
+
+func (r *ring) read(offset, tail uint64, id *uint64, price *float64, qty *uint32) {
+	*id = r.ids[offset]
+	*price = r.prices[offset]
+	*qty = r.qtys[offset]
+	atomic.StoreUint64(&r.cycleState[offset], tail+r.capacity)
+}
+
+// mpscBuffer is MPSCBuffer: producers CAS writeIndex, the one consumer owns
+// readIndex.
+type mpscBuffer struct{ ring }
+
+func newMPSCBuffer(capacity uint64) *mpscBuffer {
+	return &mpscBuffer{newRing(capacity)}
+}
+
+func (rb *mpscBuffer) Enqueue(id uint64, price float64, qty uint32) bool {
+	for {
+		head := atomic.LoadUint64(&rb.writeIndex)
+		offset := head & rb.mask
+		diff := rb.diff(offset, head)
+		if diff == 0 {
+			if atomic.CompareAndSwapUint64(&rb.writeIndex, head, head+1) {
+				rb.write(offset, head, id, price, qty)
+				return true
+			}
+		} else if diff < 0 {
+			return false
+		}
+	}
+}
+
+func (rb *mpscBuffer) Dequeue(id *uint64, price *float64, qty *uint32) bool {
+	tail := rb.readIndex
+	offset := tail & rb.mask
+	if rb.diff(offset, tail+1) != 0 {
+		return false
+	}
+	rb.read(offset, tail, id, price, qty)
+	rb.readIndex = tail + 1
+	return true
+}
+
+// spmcBuffer is SPMCBuffer: the one producer owns writeIndex, consumers CAS
+// readIndex.
+type spmcBuffer struct{ ring }
+
+func newSPMCBuffer(capacity uint64) *spmcBuffer {
+	return &spmcBuffer{newRing(capacity)}
+}
+
+func (rb *spmcBuffer) Enqueue(id uint64, price float64, qty uint32) bool {
+	head := rb.writeIndex
+	offset := head & rb.mask
+	if rb.diff(offset, head) != 0 {
+		return false
+	}
+	rb.write(offset, head, id, price, qty)
+	rb.writeIndex = head + 1
+	return true
+}
+
+func (rb *spmcBuffer) Dequeue(id *uint64, price *float64, qty *uint32) bool {
+	for {
+		tail := atomic.LoadUint64(&rb.readIndex)
+		offset := tail & rb.mask
+		diff := rb.diff(offset, tail+1)
+		if diff == 0 {
+			if atomic.CompareAndSwapUint64(&rb.readIndex, tail, tail+1) {
+				rb.read(offset, tail, id, price, qty)
+				return true
+			}
+		} else if diff < 0 {
+			return false
+		}
+	}
+}
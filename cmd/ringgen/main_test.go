@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestGenerate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quote.go")
+	src := "package main\n\ntype Quote struct {\n\tSeq uint64 `ring:\"column\"`\n\tVenue uint16\n}\n"
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := parse(path, "Quote")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(s.Columns) != 1 || s.Columns[0].Name != "Seq" || len(s.Others) != 1 || s.Others[0].Name != "Venue" {
+		t.Fatalf("parse split the fields into %v and %v", s.Columns, s.Others)
+	}
+
+	out, err := generate(s)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	code := strings.Join(strings.Fields(string(out)), " ")
+	for _, want := range []string{
+		"_ [CacheLineSize]byte",
+		"_ [CacheLineSize - 8]byte",
+		`checkCapacity("NewQuoteRingBuffer", capacity)`,
+		"colSeq []uint64",
+		"rest []quoteRest",
+		"cycleState []cycle",
+		"cycleDiff(loadCycle(&rb.cycleState[offset]), head)",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code lacks %q", want)
+		}
+	}
+}
+
+// TestGenerateOrder compares the buffer generated for the package's Order
+// type with testdata/order_ring.golden. After an intended change to the
+// template, regenerate the file and review the diff:
+//
+//	go test ./cmd/ringgen -run Order -update
+func TestGenerateOrder(t *testing.T) {
+	s, err := parse(filepath.Join("..", "..", "main.go"), "Order")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	got, err := generate(s)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	golden := filepath.Join("testdata", "order_ring.golden")
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("generated code differs from %s; rerun with -update if the change is intended:\n%s", golden, got)
+	}
+}
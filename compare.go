@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BenchQueue is the surface the benchmark needs to drive another MPMC queue
+// side by side with the ring buffer. Both methods must be safe for concurrent
+// use and must return immediately rather than block.
+type BenchQueue interface {
+	Enqueue(o Order) bool
+	Dequeue() (Order, bool)
+}
+
+type namedQueue struct {
+	name     string
+	newQueue func(capacity uint64) BenchQueue
+}
+
+type result struct {
+	name string
+	ops  float64
+}
+
+var comparisonQueues []namedQueue
+
+// RegisterComparisonQueue adds a queue to the benchmark's comparison table.
+// Call it from an init function in a file behind its own build tag, so the
+// third-party dependency is only pulled in when that tag is set; see
+// compare_mutex.go for an example.
+func RegisterComparisonQueue(name string, newQueue func(capacity uint64) BenchQueue) {
+	comparisonQueues = append(comparisonQueues, namedQueue{name, newQueue})
+}
+
+func runComparisonBenchmark(ctx context.Context, nq namedQueue) float64 {
+	fmt.Printf("Running %s Benchmark...  ", nq.name)
+
+	q := nq.newQueue(BufferSize)
+	var wg sync.WaitGroup
+
+	start := time.Now()
+
+	msgsPerProducer := TotalEvents / NumProducers
+	wg.Add(NumProducers)
+	for p := 0; p < NumProducers; p++ {
+		go func() {
+			defer wg.Done()
+			for i := 0; i < msgsPerProducer && ctx.Err() == nil; i++ {
+				for !q.Enqueue(Order{ID: uint64(i), Price: 100.0, Qty: 1}) {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+
+	var producersDone atomic.Bool
+	var consumed atomic.Uint64
+
+	msgsPerConsumer := TotalEvents / NumConsumers
+	var consumerWg sync.WaitGroup
+	consumerWg.Add(NumConsumers)
+	for c := 0; c < NumConsumers; c++ {
+		go func() {
+			defer consumerWg.Done()
+			processed := 0
+			for processed < msgsPerConsumer {
+				if _, ok := q.Dequeue(); ok {
+					processed++
+				} else if producersDone.Load() {
+					break
+				} else {
+					runtime.Gosched()
+				}
+			}
+			consumed.Add(uint64(processed))
+		}()
+	}
+
+	wg.Wait()
+	if ctx.Err() != nil {
+		producersDone.Store(true)
+	}
+	consumerWg.Wait()
+
+	duration := time.Since(start)
+	ops := float64(consumed.Load()) / duration.Seconds()
+	reportProgress(ctx, duration, consumed.Load())
+	fmt.Printf(">> %s Throughput: %.0f ops/sec\n", nq.name, ops)
+	fmt.Println("---------------------------------------------------------")
+	return ops
+}
+
+func printSummary(results []result) {
+	fmt.Println("Summary")
+	for _, r := range results {
+		fmt.Printf("  %-24s %14.0f ops/sec\n", r.name, r.ops)
+	}
+	fmt.Println("---------------------------------------------------------")
+}
@@ -0,0 +1,46 @@
+//go:build mutexqueue
+
+package main
+
+import "sync"
+
+// A mutex-guarded slice queue, registered as an example comparison baseline.
+// Build with -tags mutexqueue to include it. A third-party queue plugs in the
+// same way: wrap it in a type implementing BenchQueue and register it from
+// init in its own tagged file.
+
+type mutexQueue struct {
+	mu    sync.Mutex
+	items []Order
+	head  int
+	n     int
+}
+
+func init() {
+	RegisterComparisonQueue("Mutex Queue", func(capacity uint64) BenchQueue {
+		return &mutexQueue{items: make([]Order, capacity)}
+	})
+}
+
+func (q *mutexQueue) Enqueue(o Order) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.n == len(q.items) {
+		return false
+	}
+	q.items[(q.head+q.n)%len(q.items)] = o
+	q.n++
+	return true
+}
+
+func (q *mutexQueue) Dequeue() (Order, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.n == 0 {
+		return Order{}, false
+	}
+	o := q.items[q.head]
+	q.head = (q.head + 1) % len(q.items)
+	q.n--
+	return o, true
+}
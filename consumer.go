@@ -0,0 +1,60 @@
+package main
+
+import "context"
+
+// consumerBatch is how many items RunBatchConsumer takes per call at most.
+const consumerBatch = 64
+
+// RunConsumer dequeues items and calls handler for each one, waiting with the
+// buffer's WaitStrategy while it is empty. It returns nil once the buffer is
+// closed and drained, or ctx.Err() once ctx is done; the context is checked
+// every ctxCheckInterval iterations, busy or idle, so a steady stream cannot
+// keep it from noticing cancellation.
+func (rb *RingBuffer) RunConsumer(ctx context.Context, handler func(Order)) error {
+	var o Order
+	attempt := 0
+	for i := 0; ; i++ {
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		if rb.Dequeue(&o.ID, &o.Price, &o.Qty) {
+			handler(o)
+			attempt = 0
+			continue
+		}
+		if rb.closedEmpty() {
+			return nil
+		}
+		rb.wait.Wait(attempt)
+		attempt++
+	}
+}
+
+// RunBatchConsumer is RunConsumer handing over whatever is ready, up to
+// consumerBatch items per call, with one claim per batch. The slice is reused
+// between calls, so handler must copy anything it keeps.
+func (rb *RingBuffer) RunBatchConsumer(ctx context.Context, handler func([]Order)) error {
+	batch := make([]Order, min(consumerBatch, rb.capacity))
+	attempt := 0
+	for i := 0; ; i++ {
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		if n := rb.DrainTo(batch); n > 0 {
+			handler(batch[:n])
+			attempt = 0
+			continue
+		}
+		if rb.closedEmpty() {
+			return nil
+		}
+		rb.wait.Wait(attempt)
+		attempt++
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// ctxCheckInterval is how many failed attempts the context-aware operations
+// make between checks of ctx.Done() when the WaitStrategy does not block,
+// keeping the channel poll off the per-retry path.
+const ctxCheckInterval = 256
+
+// contextWaiter is implemented by wait strategies that block between
+// attempts. The context-aware operations call WaitContext instead of Wait so
+// a cancellation ends the wait at once rather than after the sleep.
+type contextWaiter interface {
+	WaitContext(ctx context.Context, attempt int) error
+}
+
+// EnqueueContext retries Enqueue like EnqueueBlocking until it succeeds or
+// ctx is done, in which case it returns ctx.Err(). It returns ErrClosed if the
+// buffer is closed. With a spinning or yielding WaitStrategy the context is
+// checked on the first failed attempt and every ctxCheckInterval attempts
+// after that; with SleepBackoff every sleep also watches ctx.Done().
+func (rb *RingBuffer) EnqueueContext(ctx context.Context, id uint64, price float64, qty uint32) error {
+	for attempt := 0; !rb.Enqueue(id, price, qty); attempt++ {
+		if rb.Closed() {
+			return ErrClosed
+		}
+		if err := rb.waitContext(ctx, attempt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DequeueContext retries Dequeue like DequeueBlocking until an item arrives or
+// ctx is done, in which case it returns ctx.Err(). It returns ErrClosed once
+// the buffer is closed and drained. The context is checked as in
+// EnqueueContext.
+func (rb *RingBuffer) DequeueContext(ctx context.Context) (Order, error) {
+	var o Order
+	for attempt := 0; !rb.Dequeue(&o.ID, &o.Price, &o.Qty); attempt++ {
+		if rb.closedEmpty() {
+			return Order{}, ErrClosed
+		}
+		if err := rb.waitContext(ctx, attempt); err != nil {
+			return Order{}, err
+		}
+	}
+	return o, nil
+}
+
+// waitContext waits once between attempts, returning ctx.Err() if ctx is
+// done.
+func (rb *RingBuffer) waitContext(ctx context.Context, attempt int) error {
+	if cw, ok := rb.wait.(contextWaiter); ok {
+		return cw.WaitContext(ctx, attempt)
+	}
+	if attempt%ctxCheckInterval == 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	rb.wait.Wait(attempt)
+	return nil
+}
+
+func (s sleepBackoff) WaitContext(ctx context.Context, attempt int) error {
+	t := time.NewTimer(s.delay(attempt))
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestContextCancel cancels the context of a call blocked on a full or empty
+// buffer and checks that it returns context.Canceled promptly, for each wait
+// strategy. The sleep is long enough that a cancel seen only between sleeps
+// would blow the bound.
+func TestContextCancel(t *testing.T) {
+	const bound = 500 * time.Millisecond
+	strategies := []struct {
+		name string
+		ws   WaitStrategy
+	}{
+		{"Yield", Yield},
+		{"BusySpin", BusySpin},
+		{"SleepBackoff", SleepBackoff(time.Millisecond, 10*time.Second)},
+	}
+	calls := []struct {
+		name string
+		fill bool
+		call func(ctx context.Context, rb *RingBuffer) error
+	}{
+		{"EnqueueContext/full", true, func(ctx context.Context, rb *RingBuffer) error {
+			return rb.EnqueueContext(ctx, 1, 0, 0)
+		}},
+		{"DequeueContext/empty", false, func(ctx context.Context, rb *RingBuffer) error {
+			_, err := rb.DequeueContext(ctx)
+			return err
+		}},
+	}
+	for _, ws := range strategies {
+		for _, c := range calls {
+			t.Run(ws.name+"/"+c.name, func(t *testing.T) {
+				rb := NewbufferOpts(4, WithWaitStrategy(ws.ws))
+				if c.fill {
+					for rb.Enqueue(0, 0, 0) {
+					}
+				}
+				ctx, cancel := context.WithCancel(context.Background())
+				result := make(chan error, 1)
+				go func() { result <- c.call(ctx, rb) }()
+
+				time.Sleep(20 * time.Millisecond)
+				cancel()
+				start := time.Now()
+				select {
+				case err := <-result:
+					if !errors.Is(err, context.Canceled) {
+						t.Fatalf("returned %v, want context.Canceled", err)
+					}
+					if elapsed := time.Since(start); elapsed > bound {
+						t.Fatalf("returned %v after the cancel, want under %v", elapsed, bound)
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatal("did not return after the cancel")
+				}
+			})
+		}
+	}
+}
+
+func TestContextDeadline(t *testing.T) {
+	rb := NewbufferOpts(4, WithWaitStrategy(SleepBackoff(time.Millisecond, time.Second)))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := rb.DequeueContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("DequeueContext = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestContextSucceeds(t *testing.T) {
+	rb := Newbuffer(4)
+	ctx := context.Background()
+	if err := rb.EnqueueContext(ctx, 7, 1.5, 2); err != nil {
+		t.Fatalf("EnqueueContext: %v", err)
+	}
+	o, err := rb.DequeueContext(ctx)
+	if err != nil || o != (Order{ID: 7, Price: 1.5, Qty: 2}) {
+		t.Fatalf("DequeueContext = %+v, %v", o, err)
+	}
+	rb.Close()
+	if _, err := rb.DequeueContext(ctx); !errors.Is(err, ErrClosed) {
+		t.Fatalf("DequeueContext after Close = %v, want ErrClosed", err)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import "fmt"
+
+// OrdersToColumns splits orders into the three parallel slices used by the
+// batch API.
+func OrdersToColumns(orders []Order) (ids []uint64, prices []float64, qtys []uint32) {
+	ids = make([]uint64, len(orders))
+	prices = make([]float64, len(orders))
+	qtys = make([]uint32, len(orders))
+	for i, o := range orders {
+		ids[i] = o.ID
+		prices[i] = o.Price
+		qtys[i] = o.Qty
+	}
+	return ids, prices, qtys
+}
+
+// ColumnsToOrders zips the three parallel slices back into orders. It panics
+// if the slices differ in length, which means the columns were not filled
+// together and some orders would come out with fields from another order or
+// none at all.
+func ColumnsToOrders(ids []uint64, prices []float64, qtys []uint32) []Order {
+	if len(prices) != len(ids) || len(qtys) != len(ids) {
+		panic(fmt.Sprintf("ring: ColumnsToOrders with %d ids, %d prices and %d qtys", len(ids), len(prices), len(qtys)))
+	}
+	orders := make([]Order, len(ids))
+	for i := range orders {
+		orders[i] = Order{ID: ids[i], Price: prices[i], Qty: qtys[i]}
+	}
+	return orders
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestColumnsRoundTrip(t *testing.T) {
+	for _, n := range []int{0, 1, 100_000} {
+		orders := make([]Order, n)
+		for i := range orders {
+			orders[i] = Order{ID: uint64(i), Price: float64(i) / 4, Qty: uint32(n - i)}
+		}
+
+		ids, prices, qtys := OrdersToColumns(orders)
+		if len(ids) != n || len(prices) != n || len(qtys) != n {
+			t.Fatalf("n=%d: column lengths %d, %d, %d", n, len(ids), len(prices), len(qtys))
+		}
+		for i, o := range orders {
+			if ids[i] != o.ID || prices[i] != o.Price || qtys[i] != o.Qty {
+				t.Fatalf("n=%d: column %d = %d, %v, %d, want %+v", n, i, ids[i], prices[i], qtys[i], o)
+			}
+		}
+		if got := ColumnsToOrders(ids, prices, qtys); !slices.Equal(got, orders) {
+			t.Fatalf("n=%d: round trip changed the orders", n)
+		}
+	}
+}
+
+func TestColumnsToOrdersMismatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		ids    []uint64
+		prices []float64
+		qtys   []uint32
+	}{
+		{"short ids", make([]uint64, 1), make([]float64, 2), make([]uint32, 2)},
+		{"short prices", make([]uint64, 2), make([]float64, 1), make([]uint32, 2)},
+		{"short qtys", make([]uint64, 2), make([]float64, 2), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("ColumnsToOrders accepted columns of different lengths")
+				}
+			}()
+			ColumnsToOrders(tt.ids, tt.prices, tt.qtys)
+		})
+	}
+}
+
+func BenchmarkColumnsConversion(b *testing.B) {
+	for _, n := range []int{1, 64, 4096} {
+		orders := make([]Order, n)
+		ids, prices, qtys := OrdersToColumns(orders)
+		b.Run(fmt.Sprintf("OrdersToColumns/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				OrdersToColumns(orders)
+			}
+			b.ReportMetric(float64(b.N*n)/b.Elapsed().Seconds(), "orders/s")
+		})
+		b.Run(fmt.Sprintf("ColumnsToOrders/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				ColumnsToOrders(ids, prices, qtys)
+			}
+			b.ReportMetric(float64(b.N*n)/b.Elapsed().Seconds(), "orders/s")
+		})
+	}
+}
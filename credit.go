@@ -0,0 +1,81 @@
+package main
+
+import "sync/atomic"
+
+// CreditBuffer admits items by weight as well as by count. It holds up to
+// capacity items, like any ring, and on top of that the weights of the items
+// it holds may not add up to more than its credit budget. Enqueue takes an
+// item's weight out of the budget before claiming a slot and gives it back if
+// no slot is free; Dequeue returns the weight once the item is out. So
+// whichever runs out first rejects the item: a few heavy items can exhaust
+// the credits with slots to spare, and many light ones can fill the slots
+// with credits to spare. A weight larger than the whole budget never fits.
+//
+// The items and their weights travel through a RingBufferG, so ordering and
+// the lock-free guarantees are the ring's. Only the credit count is shared
+// on top, as one atomic word.
+type CreditBuffer struct {
+	credits uint64
+	_       [CacheLineSize - 8]byte
+
+	budget uint64
+	rb     *RingBufferG[weightedOrder]
+}
+
+type weightedOrder struct {
+	Order
+	weight uint64
+}
+
+// NewCreditBuffer returns a buffer of capacity slots, which must be a power
+// of two, sharing budget credits.
+func NewCreditBuffer(capacity, budget uint64) *CreditBuffer {
+	return &CreditBuffer{
+		credits: budget,
+		budget:  budget,
+		rb:      NewbufferG[weightedOrder](capacity),
+	}
+}
+
+// Enqueue adds o at the given weight and reports whether it was admitted. It
+// fails if fewer than weight credits are left or every slot is taken.
+func (cb *CreditBuffer) Enqueue(o Order, weight uint64) bool {
+	for {
+		avail := atomic.LoadUint64(&cb.credits)
+		if avail < weight {
+			return false
+		}
+		if atomic.CompareAndSwapUint64(&cb.credits, avail, avail-weight) {
+			break
+		}
+	}
+
+	if !cb.rb.Enqueue(weightedOrder{Order: o, weight: weight}) {
+		atomic.AddUint64(&cb.credits, weight)
+		return false
+	}
+	return true
+}
+
+// Dequeue removes the oldest item, returns its weight to the budget and
+// reports the item and its weight, or false if the buffer is empty.
+func (cb *CreditBuffer) Dequeue() (Order, uint64, bool) {
+	w, ok := cb.rb.Dequeue()
+	if !ok {
+		return Order{}, 0, false
+	}
+	atomic.AddUint64(&cb.credits, w.weight)
+	return w.Order, w.weight, true
+}
+
+// Credits returns how many credits are free. Under concurrent use it is a
+// snapshot, and it can briefly dip by the weight of an Enqueue that is about
+// to find the slots full.
+func (cb *CreditBuffer) Credits() uint64 {
+	return atomic.LoadUint64(&cb.credits)
+}
+
+// Budget returns the total credits the buffer was built with.
+func (cb *CreditBuffer) Budget() uint64 {
+	return cb.budget
+}
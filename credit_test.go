@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+// TestCreditBufferWeights mixes heavy and light items: once the heavy ones
+// have spent the budget, further heavy items are rejected while light ones
+// still fit, and every rejection leaves the credits where they were.
+func TestCreditBufferWeights(t *testing.T) {
+	cb := NewCreditBuffer(8, 10)
+	if !cb.Enqueue(Order{ID: 1}, 4) || !cb.Enqueue(Order{ID: 2}, 4) {
+		t.Fatal("heavy items rejected within the budget")
+	}
+	if cb.Enqueue(Order{ID: 3}, 4) {
+		t.Fatal("heavy item admitted with 2 credits left")
+	}
+	if got := cb.Credits(); got != 2 {
+		t.Fatalf("Credits() = %d after a rejected Enqueue, want 2", got)
+	}
+	if !cb.Enqueue(Order{ID: 4}, 1) || !cb.Enqueue(Order{ID: 5}, 1) {
+		t.Fatal("light items rejected with credits left")
+	}
+	if cb.Enqueue(Order{ID: 6}, 1) {
+		t.Fatal("light item admitted with the budget spent")
+	}
+	if cb.Enqueue(Order{ID: 7}, 11) {
+		t.Fatal("item heavier than the whole budget admitted")
+	}
+
+	for _, want := range []struct {
+		id, weight uint64
+	}{{1, 4}, {2, 4}, {4, 1}, {5, 1}} {
+		o, w, ok := cb.Dequeue()
+		if !ok || o.ID != want.id || w != want.weight {
+			t.Fatalf("Dequeue = %d, weight %d, %v; want %d, weight %d", o.ID, w, ok, want.id, want.weight)
+		}
+	}
+	if _, _, ok := cb.Dequeue(); ok {
+		t.Fatal("Dequeue succeeded on an empty buffer")
+	}
+	if got := cb.Credits(); got != cb.Budget() {
+		t.Fatalf("Credits() = %d once drained, want the budget %d", got, cb.Budget())
+	}
+}
+
+// TestCreditBufferSlotsFull runs out of slots before credits: the refused
+// item's weight must go back to the budget.
+func TestCreditBufferSlotsFull(t *testing.T) {
+	cb := NewCreditBuffer(2, 100)
+	for i := range uint64(2) {
+		if !cb.Enqueue(Order{ID: i}, 1) {
+			t.Fatalf("Enqueue(%d) rejected with slots free", i)
+		}
+	}
+	if cb.Enqueue(Order{ID: 2}, 1) {
+		t.Fatal("Enqueue admitted with every slot taken")
+	}
+	if got := cb.Credits(); got != 98 {
+		t.Fatalf("Credits() = %d after a slot-full rejection, want 98", got)
+	}
+}
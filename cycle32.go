@@ -0,0 +1,36 @@
+//go:build cycle32
+
+package main
+
+import "sync/atomic"
+
+// cycle is the per-slot sequence stamp, truncated to 32 bits to halve the
+// cycle-state memory. Stamps wrap every 2^32 sequences, so comparisons are
+// done modulo 2^32 and are only meaningful while the true distance between a
+// stamp and the sequence it is compared against stays below 2^31. A slot's
+// stamp is always within one lap of the index that reads it, so capacity is
+// limited to 2^30. A goroutine stalled for 2^31 operations can mistake a stale
+// stamp for a current one, but the CAS on the full 64-bit writeIndex or
+// readIndex that follows then fails, so the run length itself is unbounded.
+type cycle = uint32
+
+const maxCapacity = 1 << 30
+
+// As in cycle64.go, these atomics are also what order the plain payload
+// writes before the reads that observe the stamp.
+func loadCycle(p *cycle) cycle {
+	return atomic.LoadUint32(p)
+}
+
+func storeCycle(p *cycle, seq uint64) {
+	atomic.StoreUint32(p, uint32(seq))
+}
+
+func casCycle(p *cycle, old, new uint64) bool {
+	return atomic.CompareAndSwapUint32(p, uint32(old), uint32(new))
+}
+
+// cycleDiff returns how far the slot stamp c is ahead of seq, modulo 2^32.
+func cycleDiff(c cycle, seq uint64) int64 {
+	return int64(int32(c - uint32(seq)))
+}
@@ -0,0 +1,36 @@
+//go:build !cycle32
+
+package main
+
+import "sync/atomic"
+
+// cycle is the per-slot sequence stamp. By default it is a full uint64, which
+// never wraps in practice.
+type cycle = uint64
+
+const maxCapacity = 1 << 62
+
+// loadCycle and storeCycle are what publish a slot's payload. The ids,
+// prices and qtys columns are plain memory, written before the storeCycle that
+// stamps the slot and read after a loadCycle that observed the stamp. The Go
+// memory model makes an atomic store synchronized before any atomic load that
+// observes its value, so those payload writes happen before the reads on every
+// architecture, arm64 included; the compiler emits the barriers (STLR/LDAR on
+// arm64). The same edge runs the other way when a consumer's release stamp
+// hands the slot back to producers. The payload itself needs no atomics.
+func loadCycle(p *cycle) cycle {
+	return atomic.LoadUint64(p)
+}
+
+func storeCycle(p *cycle, seq uint64) {
+	atomic.StoreUint64(p, seq)
+}
+
+func casCycle(p *cycle, old, new uint64) bool {
+	return atomic.CompareAndSwapUint64(p, old, new)
+}
+
+// cycleDiff returns how far the slot stamp c is ahead of seq.
+func cycleDiff(c cycle, seq uint64) int64 {
+	return int64(c - seq)
+}
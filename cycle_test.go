@@ -0,0 +1,145 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// startAt moves a fresh buffer's indices to start, as if start items had
+// already passed through it, and stamps every slot free for its next
+// sequence.
+func startAt(rb *RingBuffer, start uint64) {
+	rb.writeIndex, rb.readIndex = start, start
+	for seq := start; seq < start+rb.capacity; seq++ {
+		storeCycle(&rb.cycleState[seq&rb.mask], seq)
+	}
+}
+
+// cycleWrapStart is a few laps short of the point where a 32-bit stamp
+// wraps, so the tests below cross it with either cycle type.
+const cycleWrapStart = 1<<32 - 100
+
+func TestCycleDiffAcrossWrap(t *testing.T) {
+	for _, seq := range []uint64{0, 1<<32 - 1, 1 << 32, 1<<32 + 1, 5 << 32} {
+		for _, d := range []int64{-1 << 20, -1, 0, 1, 1 << 20} {
+			if got := cycleDiff(cycle(seq+uint64(d)), seq); got != d {
+				t.Errorf("cycleDiff(seq%+d, %#x) = %d, want %d", d, seq, got, d)
+			}
+		}
+	}
+}
+
+// TestCycleWrapLaps runs single and batch operations for many laps of a
+// small buffer across the 32-bit stamp wrap, checking FIFO order and the
+// full and empty checks on every lap. With -tags cycle32 this exercises the
+// modular comparisons; with 64-bit stamps it is a plain many-lap test.
+func TestCycleWrapLaps(t *testing.T) {
+	const capacity, laps = 8, 64
+	rb := Newbuffer(capacity)
+	startAt(rb, cycleWrapStart)
+
+	ids := make([]uint64, 4)
+	prices := make([]float64, 4)
+	qtys := make([]uint32, 4)
+	next, want := uint64(0), uint64(0)
+	for lap := range laps {
+		for i := range uint64(4) {
+			ids[i] = next + i
+		}
+		if rb.EnqueueBatch(ids, prices, qtys) != 4 {
+			t.Fatalf("lap %d: EnqueueBatch into an empty buffer failed", lap)
+		}
+		next += 4
+		for range 4 {
+			if !rb.Enqueue(next, 0, 0) {
+				t.Fatalf("lap %d: Enqueue into a buffer with room failed", lap)
+			}
+			next++
+		}
+		if rb.Enqueue(next, 0, 0) {
+			t.Fatalf("lap %d: Enqueue into a full buffer succeeded", lap)
+		}
+
+		if n := rb.DequeueBatch(ids, prices, qtys); n != 4 {
+			t.Fatalf("lap %d: DequeueBatch = %d, want 4", lap, n)
+		}
+		for _, id := range ids {
+			if id != want {
+				t.Fatalf("lap %d: got ID %d, want %d", lap, id, want)
+			}
+			want++
+		}
+		for range 4 {
+			o, ok := rb.DequeueOrder()
+			if !ok || o.ID != want {
+				t.Fatalf("lap %d: DequeueOrder = %d, %v, want %d", lap, o.ID, ok, want)
+			}
+			want++
+		}
+		if _, ok := rb.DequeueOrder(); ok {
+			t.Fatalf("lap %d: DequeueOrder from an empty buffer succeeded", lap)
+		}
+	}
+	if head := rb.writeIndex; head < 1<<32 {
+		t.Fatalf("writeIndex ended at %#x without crossing 2^32", head)
+	}
+}
+
+// TestCycleWrapConcurrent crosses the stamp wrap with two producers and two
+// consumers racing, checking that each producer's items arrive exactly once
+// and in order.
+func TestCycleWrapConcurrent(t *testing.T) {
+	const producers, consumers, perProducer = 2, 2, 20_000
+	rb := Newbuffer(16)
+	startAt(rb, cycleWrapStart)
+
+	var pwg sync.WaitGroup
+	pwg.Add(producers)
+	for p := range uint64(producers) {
+		go func() {
+			defer pwg.Done()
+			for i := range uint64(perProducer) {
+				for !rb.Enqueue(p<<32|i, 0, 0) {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+
+	var mu sync.Mutex
+	next := make([]uint64, producers)
+	received := 0
+	var cwg sync.WaitGroup
+	cwg.Add(consumers)
+	for range consumers {
+		go func() {
+			defer cwg.Done()
+			for {
+				mu.Lock()
+				if received == producers*perProducer {
+					mu.Unlock()
+					return
+				}
+				// Checking order needs the dequeue and the check to happen
+				// together, so consumers take turns here; they still race
+				// the producers for every slot.
+				o, ok := rb.DequeueOrder()
+				if ok {
+					p, i := o.ID>>32, o.ID&(1<<32-1)
+					if i != next[p] {
+						t.Errorf("producer %d: got item %d, want %d", p, i, next[p])
+					}
+					next[p] = i + 1
+					received++
+				}
+				mu.Unlock()
+				if !ok {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+	pwg.Wait()
+	cwg.Wait()
+}
@@ -0,0 +1,108 @@
+package main
+
+import "sync"
+
+const dedupShards = 64
+
+type dedupShard struct {
+	mu  sync.Mutex
+	ids map[uint64]struct{}
+	_   [CacheLineSize - 16]byte
+}
+
+// DedupBuffer wraps a RingBuffer and rejects an Enqueue whose ID is already
+// in flight, i.e. enqueued and not yet dequeued.
+//
+// The in-flight set holds at most one entry per buffered item, so its memory
+// grows with the buffer's capacity (roughly 40-50 bytes per ID in the Go map).
+// Every operation takes one shard mutex on top of the ring's CAS; IDs are
+// spread over 64 padded shards so producers only contend when their IDs hash
+// to the same shard.
+type DedupBuffer struct {
+	rb     *RingBuffer
+	shards [dedupShards]dedupShard
+}
+
+func NewDedupBuffer(capacity uint64) *DedupBuffer {
+	d := &DedupBuffer{rb: Newbuffer(capacity)}
+	for i := range d.shards {
+		d.shards[i].ids = make(map[uint64]struct{})
+	}
+	return d
+}
+
+func (d *DedupBuffer) shard(id uint64) *dedupShard {
+	return &d.shards[(id*0x9E3779B97F4A7C15)>>58]
+}
+
+func (d *DedupBuffer) Enqueue(id uint64, price float64, qty uint32) bool {
+	s := d.shard(id)
+
+	s.mu.Lock()
+	if _, ok := s.ids[id]; ok {
+		s.mu.Unlock()
+		return false
+	}
+	s.ids[id] = struct{}{}
+	s.mu.Unlock()
+
+	if !d.rb.Enqueue(id, price, qty) {
+		s.mu.Lock()
+		delete(s.ids, id)
+		s.mu.Unlock()
+		return false
+	}
+	return true
+}
+
+func (d *DedupBuffer) Dequeue(id *uint64, price *float64, qty *uint32) bool {
+	if !d.rb.Dequeue(id, price, qty) {
+		return false
+	}
+
+	s := d.shard(*id)
+	s.mu.Lock()
+	delete(s.ids, *id)
+	s.mu.Unlock()
+	return true
+}
+
+// InFlight reports whether id is currently enqueued and not yet dequeued.
+func (d *DedupBuffer) InFlight(id uint64) bool {
+	s := d.shard(id)
+	s.mu.Lock()
+	_, ok := s.ids[id]
+	s.mu.Unlock()
+	return ok
+}
+
+// distinctRuns returns how many runs of equal consecutive IDs ids holds,
+// the number of items WithBatchDedup stores for the batch.
+func distinctRuns(ids []uint64) uint64 {
+	n := uint64(0)
+	for i, id := range ids {
+		if i == 0 || id != ids[i-1] {
+			n++
+		}
+	}
+	return n
+}
+
+// copyInDistinct is copyIn for WithBatchDedup: it writes the first item of
+// each run of equal IDs to consecutive slots from head. The caller must own
+// distinctRuns(ids) slots.
+func (rb *RingBuffer) copyInDistinct(head uint64, ids []uint64, prices []float64, qtys []uint32) {
+	seq := head
+	for i, id := range ids {
+		if i > 0 && id == ids[i-1] {
+			continue
+		}
+		offset := seq & rb.mask
+		rb.ids[offset] = id
+		rb.prices[offset] = prices[i]
+		rb.qtys[offset] = qtys[i]
+		storeCycle(&rb.cycleState[offset], seq+1)
+		rb.oplog.record(OpEnqueue, seq, id, prices[i], qtys[i])
+		seq++
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBatchDedup(t *testing.T) {
+	rb := NewbufferOpts(8, WithBatchDedup())
+	ids := []uint64{1, 1, 2, 3, 3, 3, 1}
+	prices := []float64{1.0, 1.1, 2.0, 3.0, 3.1, 3.2, 1.2}
+	qtys := []uint32{10, 11, 20, 30, 31, 32, 12}
+	if n := rb.EnqueueBatch(ids, prices, qtys); n != 4 {
+		t.Fatalf("EnqueueBatch = %d, want 4", n)
+	}
+
+	want := []Order{{1, 1.0, 10}, {2, 2.0, 20}, {3, 3.0, 30}, {1, 1.2, 12}}
+	for i, w := range want {
+		if o, ok := rb.DequeueOrder(); !ok || o != w {
+			t.Fatalf("item %d = %+v, %v, want %+v", i, o, ok, w)
+		}
+	}
+	if !rb.IsEmpty() {
+		t.Fatalf("Len() = %d, want 0", rb.Len())
+	}
+
+	// Dedup is per call: the same ID in the next batch is stored again.
+	rb.EnqueueBatch([]uint64{5, 5}, make([]float64, 2), make([]uint32, 2))
+	rb.EnqueueBatch([]uint64{5}, make([]float64, 1), make([]uint32, 1))
+	if rb.Len() != 2 {
+		t.Fatalf("Len() = %d after two batches of ID 5, want 2", rb.Len())
+	}
+
+	// A batch longer than the buffer fits once collapsed.
+	if err := rb.EnqueueBatchE(make([]uint64, 16), make([]float64, 16), make([]uint32, 16)); err != nil {
+		t.Fatalf("EnqueueBatchE of 16 equal IDs: %v", err)
+	}
+
+	plain := Newbuffer(8)
+	if n := plain.EnqueueBatch(ids, prices, qtys); n != uint64(len(ids)) {
+		t.Fatalf("EnqueueBatch without WithBatchDedup = %d, want %d", n, len(ids))
+	}
+}
+
+func TestDedupBuffer(t *testing.T) {
+	d := NewDedupBuffer(4)
+	if !d.Enqueue(7, 1.5, 10) {
+		t.Fatal("first Enqueue(7) failed")
+	}
+	if d.Enqueue(7, 2.5, 20) {
+		t.Fatal("second Enqueue(7) succeeded while 7 was in flight")
+	}
+	if !d.InFlight(7) || d.InFlight(8) {
+		t.Fatalf("InFlight(7), InFlight(8) = %v, %v, want true, false", d.InFlight(7), d.InFlight(8))
+	}
+
+	var id uint64
+	var price float64
+	var qty uint32
+	if !d.Dequeue(&id, &price, &qty) || id != 7 || price != 1.5 || qty != 10 {
+		t.Fatalf("Dequeue = %d, %v, %d, want the first 7", id, price, qty)
+	}
+	if d.InFlight(7) {
+		t.Fatal("7 still in flight after it was dequeued")
+	}
+	if !d.Enqueue(7, 2.5, 20) {
+		t.Fatal("Enqueue(7) after it was consumed failed")
+	}
+}
+
+// TestDedupBufferFull checks that an ID rejected by a full ring is not left
+// in the set, so it can be enqueued once there is room.
+func TestDedupBufferFull(t *testing.T) {
+	d := NewDedupBuffer(2)
+	d.Enqueue(1, 0, 0)
+	d.Enqueue(2, 0, 0)
+	if d.Enqueue(3, 0, 0) {
+		t.Fatal("Enqueue into a full buffer succeeded")
+	}
+	if d.InFlight(3) {
+		t.Fatal("ID rejected by the full ring left in the in-flight set")
+	}
+
+	var id uint64
+	var price float64
+	var qty uint32
+	d.Dequeue(&id, &price, &qty)
+	if !d.Enqueue(3, 0, 0) {
+		t.Fatal("Enqueue(3) after making room failed")
+	}
+}
+
+// TestDedupBufferConcurrent has four producers race to enqueue the same IDs
+// with no consumer running: each ID must get in exactly once.
+func TestDedupBufferConcurrent(t *testing.T) {
+	const producers, ids = 4, 512
+	d := NewDedupBuffer(1024)
+	var accepted atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(producers)
+	for range producers {
+		go func() {
+			defer wg.Done()
+			for id := range uint64(ids) {
+				if d.Enqueue(id, 0, 0) {
+					accepted.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if n := accepted.Load(); n != ids {
+		t.Fatalf("%d enqueues accepted, want %d", n, ids)
+	}
+
+	seen := make([]bool, ids)
+	var id uint64
+	var price float64
+	var qty uint32
+	for d.Dequeue(&id, &price, &qty) {
+		if seen[id] {
+			t.Fatalf("ID %d dequeued twice", id)
+		}
+		seen[id] = true
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// drainPoll is how often the Done watcher checks for a drained buffer once
+// Close has been called, after a few yields.
+const drainPoll = time.Millisecond
+
+// doneSignal backs Done. closing is closed by Close, so a watcher costs
+// nothing while the buffer is open; done is closed by the watcher, started by
+// the first call to Done, once the closed buffer has drained. stop is closed
+// by cancel to make the watcher give up, and stopped by the watcher when it
+// returns either way.
+type doneSignal struct {
+	closeOnce sync.Once
+	closing   chan struct{}
+
+	watchOnce sync.Once
+	done      chan struct{}
+	stop      chan struct{}
+	stopped   chan struct{}
+}
+
+func newDoneSignal() *doneSignal {
+	return &doneSignal{closing: make(chan struct{}), stop: make(chan struct{})}
+}
+
+// cancel stops the watcher, if Done started one, and waits for it to return,
+// so it no longer reads the buffer. No watcher can start afterwards. Reset
+// calls it before it replaces the signal.
+func (d *doneSignal) cancel() {
+	d.watchOnce.Do(func() {})
+	close(d.stop)
+	if d.stopped != nil {
+		<-d.stopped
+	}
+}
+
+// Done returns a channel that is closed once the buffer has been closed and
+// every item in it dequeued, so a supervisor can wait on <-rb.Done() for the
+// end of a graceful shutdown.
+//
+// Drained means readIndex has caught up with writeIndex and every slot has
+// been released, so no consumer is still copying an item out. Each item has
+// then been handed to exactly one consumer, exactly once; whether the
+// consumer has finished handling it is beyond what the buffer can see, so a
+// supervisor that needs that too should also wait for the consumers to
+// return, for example from RunConsumer. As with Close, producers must be done
+// before Close is called; an enqueue that slips in after the drain is never
+// signalled.
+//
+// The wait runs in a goroutine started by the first call, which sleeps until
+// Close and then polls every drainPoll. Until it has fired, the buffer is not
+// quiescent in the sense Resize requires. Reset stops it instead, and the
+// channel it was going to close is then never closed.
+func (rb *RingBuffer) Done() <-chan struct{} {
+	d := rb.done
+	d.watchOnce.Do(func() {
+		d.done = make(chan struct{})
+		d.stopped = make(chan struct{})
+		go rb.watchDrain(d)
+	})
+	return d.done
+}
+
+func (rb *RingBuffer) watchDrain(d *doneSignal) {
+	defer close(d.stopped)
+	select {
+	case <-d.closing:
+	case <-d.stop:
+		return
+	}
+
+	wait := Backoff(0, passiveSpin, drainPoll)
+	for attempt := 0; !rb.drained(); attempt++ {
+		select {
+		case <-d.stop:
+			return
+		default:
+		}
+		wait.Wait(attempt)
+	}
+	close(d.done)
+}
+
+// drained reports whether nothing is pending, in the ring or the spillover
+// queue, and every slot is free for the next lap. It scans the whole cycle
+// state, which is fine for a shutdown check but not for a hot path.
+func (rb *RingBuffer) drained() bool {
+	if !rb.spill.empty() {
+		return false
+	}
+	tail := atomic.LoadUint64(&rb.readIndex)
+	head := atomic.LoadUint64(&rb.writeIndex)
+	if head != tail {
+		return false
+	}
+	ok, stale := rb.canClaim(head, rb.capacity)
+	return ok && !stale
+}
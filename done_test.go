@@ -0,0 +1,55 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestDoneAfterDrain(t *testing.T) {
+	rb := Newbuffer(8)
+	rb.Enqueue(1, 0, 0)
+	done := rb.Done()
+	rb.Close()
+
+	select {
+	case <-done:
+		t.Fatal("Done fired with an item still buffered")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	rb.DequeueOrder()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Done did not fire after the buffer drained")
+	}
+}
+
+// TestResetStopsDoneWatcher checks that Reset does not leave the watcher of
+// the old Done channel behind, whether or not the buffer had been closed.
+func TestResetStopsDoneWatcher(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	rb := Newbuffer(8)
+	for range 10 {
+		rb.Done()
+		rb.Reset()
+	}
+	rb.Enqueue(1, 0, 0)
+	rb.Done()
+	rb.Close()
+	rb.Reset()
+
+	if after := runtime.NumGoroutine(); after > before {
+		t.Fatalf("%d goroutines after Reset, %d before", after, before)
+	}
+
+	done := rb.Done()
+	rb.Close()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Done did not fire on the reset, empty, closed buffer")
+	}
+}
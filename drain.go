@@ -0,0 +1,58 @@
+package main
+
+import "sync/atomic"
+
+// DrainTo dequeues the run of published items at the head of the buffer, up
+// to len(dst), into dst with a single claim and returns how many it took. The
+// run is contiguous: it stops at the first slot that is not yet published, so
+// an item claimed by a slow producer ends the drain even if later slots are
+// ready. Items enqueued while DrainTo runs may or may not be included.
+func (rb *RingBuffer) DrainTo(dst []Order) int {
+	limit := min(uint64(len(dst)), rb.capacity)
+	if limit == 0 {
+		return 0
+	}
+
+	for {
+		tail := atomic.LoadUint64(&rb.readIndex)
+		count, stale := rb.readable(tail, limit)
+		if stale {
+			rb.stats.addDequeueRetry()
+			continue
+		}
+		if count == 0 {
+			rb.stats.addEmpty()
+			return 0
+		}
+
+		if atomic.CompareAndSwapUint64(&rb.readIndex, tail, tail+count) {
+			rb.copyOutOrders(tail, dst[:count])
+			rb.stats.addDequeued(count)
+			return int(count)
+		}
+		rb.stats.addReadCASFailure()
+	}
+}
+
+// DrainAll is DrainTo into a newly allocated slice sized for what the buffer
+// held when it was called.
+func (rb *RingBuffer) DrainAll() []Order {
+	n := rb.Len()
+	if n == 0 {
+		return nil
+	}
+	dst := make([]Order, n)
+	return dst[:rb.DrainTo(dst)]
+}
+
+// copyOutOrders reads len(dst) items starting at sequence tail into dst and
+// releases their slots. The caller must own the range.
+func (rb *RingBuffer) copyOutOrders(tail uint64, dst []Order) {
+	for i := range dst {
+		seq := tail + uint64(i)
+		offset := seq & rb.mask
+
+		dst[i] = Order{ID: rb.ids[offset], Price: rb.prices[offset], Qty: rb.qtys[offset]}
+		storeCycle(&rb.cycleState[offset], seq+rb.capacity)
+	}
+}
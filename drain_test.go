@@ -0,0 +1,86 @@
+package main
+
+import "testing"
+
+func TestDrainTo(t *testing.T) {
+	tests := []struct {
+		name    string
+		pending int
+		dst     int
+		want    int
+	}{
+		{"empty", 0, 8, 0},
+		{"partially full", 3, 8, 3},
+		{"exactly full", 8, 8, 8},
+		{"dst shorter than pending", 8, 5, 5},
+		{"empty dst", 3, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rb := Newbuffer(8)
+			// Move the indices so the run wraps past the end of the arrays.
+			for i := range uint64(6) {
+				rb.Enqueue(i, 0, 0)
+				rb.DequeueOrder()
+			}
+			for i := range tt.pending {
+				rb.Enqueue(uint64(100+i), float64(i), uint32(i))
+			}
+
+			dst := make([]Order, tt.dst)
+			n := rb.DrainTo(dst)
+			if n != tt.want {
+				t.Fatalf("DrainTo = %d, want %d", n, tt.want)
+			}
+			for i, o := range dst[:n] {
+				if o != (Order{ID: uint64(100 + i), Price: float64(i), Qty: uint32(i)}) {
+					t.Fatalf("dst[%d] = %+v", i, o)
+				}
+			}
+			if got := int(rb.Len()); got != tt.pending-n {
+				t.Fatalf("Len() = %d after DrainTo, want %d", got, tt.pending-n)
+			}
+		})
+	}
+}
+
+// TestDrainToStopsAtUnpublished checks that the drain ends at a slot a
+// producer has claimed but not published, even with later slots ready.
+func TestDrainToStopsAtUnpublished(t *testing.T) {
+	rb := Newbuffer(8)
+	rb.Enqueue(1, 0, 0)
+	seq, _ := rb.Claim()
+	rb.Enqueue(3, 0, 0)
+
+	dst := make([]Order, 8)
+	if n := rb.DrainTo(dst); n != 1 || dst[0].ID != 1 {
+		t.Fatalf("DrainTo = %d (first %d), want just item 1", n, dst[0].ID)
+	}
+	rb.SetID(seq, 2)
+	rb.Publish(seq)
+	if n := rb.DrainTo(dst); n != 2 || dst[0].ID != 2 || dst[1].ID != 3 {
+		t.Fatalf("DrainTo after Publish = %d, %v", n, dst[:n])
+	}
+}
+
+func TestDrainAll(t *testing.T) {
+	rb := Newbuffer(4)
+	if got := rb.DrainAll(); got != nil {
+		t.Fatalf("DrainAll of an empty buffer = %v, want nil", got)
+	}
+	for i := range uint64(4) {
+		rb.Enqueue(i, 0, 0)
+	}
+	got := rb.DrainAll()
+	if len(got) != 4 {
+		t.Fatalf("DrainAll of a full buffer returned %d items, want 4", len(got))
+	}
+	for i, o := range got {
+		if o.ID != uint64(i) {
+			t.Fatalf("item %d has ID %d", i, o.ID)
+		}
+	}
+	if !rb.IsEmpty() {
+		t.Fatalf("Len() = %d after DrainAll", rb.Len())
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+var (
+	ErrBatchTooLarge = errors.New("ring: batch larger than buffer capacity")
+	ErrClosed        = errors.New("ring: buffer closed")
+	ErrFull          = errors.New("ring: buffer full")
+
+	ErrInvalidCapacity = errors.New("ring: capacity must be a power of two, at least 2 and within the cycle-state limit")
+	ErrResizeTooSmall  = errors.New("ring: new capacity cannot hold the pending items")
+	ErrResizeBusy      = errors.New("ring: resize with operations in flight")
+	ErrMarshalBusy     = errors.New("ring: marshal with operations in flight")
+	ErrInvalidEncoding = errors.New("ring: malformed buffer encoding")
+	ErrRecordTooLarge  = errors.New("ring: record larger than the stream can ever hold")
+)
+
+// BufferError reports a failed operation together with the buffer's indices
+// at the time it failed. Err is the underlying cause and can be matched with
+// errors.Is.
+type BufferError struct {
+	Op         string
+	ReadIndex  uint64
+	WriteIndex uint64
+	Capacity   uint64
+	Attempted  uint64
+	Err        error
+}
+
+func (e *BufferError) Error() string {
+	return fmt.Sprintf("%s of %d: %v (read=%d write=%d capacity=%d)",
+		e.Op, e.Attempted, e.Err, e.ReadIndex, e.WriteIndex, e.Capacity)
+}
+
+func (e *BufferError) Unwrap() error {
+	return e.Err
+}
+
+func (rb *RingBuffer) newError(op string, attempted uint64, err error) *BufferError {
+	return &BufferError{
+		Op:         op,
+		ReadIndex:  atomic.LoadUint64(&rb.readIndex),
+		WriteIndex: atomic.LoadUint64(&rb.writeIndex),
+		Capacity:   rb.capacity,
+		Attempted:  attempted,
+		Err:        err,
+	}
+}
+
+// EnqueueE is Enqueue reporting why it failed: ErrClosed or ErrFull. The
+// sentinels are returned as they are, without a BufferError, so a full buffer
+// costs no allocation.
+func (rb *RingBuffer) EnqueueE(id uint64, price float64, qty uint32) error {
+	if rb.Enqueue(id, price, qty) {
+		return nil
+	}
+	if rb.Closed() {
+		return ErrClosed
+	}
+	return ErrFull
+}
+
+// EnqueueBatchE is EnqueueBatch reporting why it failed. A batch larger than
+// the buffer returns a *BufferError wrapping ErrBatchTooLarge instead of
+// panicking; otherwise it returns ErrClosed or ErrFull as EnqueueE does.
+func (rb *RingBuffer) EnqueueBatchE(ids []uint64, prices []float64, qtys []uint32) error {
+	count := uint64(len(ids))
+	if rb.batchDedup {
+		count = distinctRuns(ids)
+	}
+	if count > rb.capacity {
+		return rb.newError("EnqueueBatchE", count, ErrBatchTooLarge)
+	}
+	if count == 0 || rb.EnqueueBatch(ids, prices, qtys) > 0 {
+		return nil
+	}
+	if rb.Closed() {
+		return ErrClosed
+	}
+	return ErrFull
+}
+
+// DequeueBatchE is DequeueBatch returning an error instead of panicking: a
+// *BufferError wrapping ErrBatchTooLarge for a batch larger than the buffer,
+// or ErrClosed once the buffer is closed and drained. An open, empty buffer
+// is not an error; it returns 0 and nil.
+func (rb *RingBuffer) DequeueBatchE(ids []uint64, prices []float64, qtys []uint32) (uint64, error) {
+	limit := uint64(len(ids))
+	if limit > rb.capacity {
+		return 0, rb.newError("DequeueBatchE", limit, ErrBatchTooLarge)
+	}
+	if limit == 0 {
+		return 0, nil
+	}
+	if n := rb.DequeueBatch(ids, prices, qtys); n > 0 {
+		return n, nil
+	}
+	if rb.closedEmpty() {
+		return 0, ErrClosed
+	}
+	return 0, nil
+}
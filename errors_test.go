@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestBufferErrorFields checks that a BufferError carries the operation, the
+// indices at the time of the failure and the attempted size.
+func TestBufferErrorFields(t *testing.T) {
+	rb := Newbuffer(8)
+	for i := range uint64(5) {
+		rb.Enqueue(i, 0, 0)
+	}
+	rb.DequeueOrder()
+	rb.DequeueOrder()
+
+	big := 9
+	ids, prices, qtys := make([]uint64, big), make([]float64, big), make([]uint32, big)
+	tests := []struct {
+		op   string
+		call func() error
+	}{
+		{"EnqueueBatchE", func() error { return rb.EnqueueBatchE(ids, prices, qtys) }},
+		{"DequeueBatchE", func() error { _, err := rb.DequeueBatchE(ids, prices, qtys); return err }},
+	}
+	for _, tt := range tests {
+		err := tt.call()
+		var be *BufferError
+		if !errors.As(err, &be) {
+			t.Fatalf("%s = %v, want a *BufferError", tt.op, err)
+		}
+		want := BufferError{Op: tt.op, ReadIndex: 2, WriteIndex: 5, Capacity: 8, Attempted: 9, Err: ErrBatchTooLarge}
+		if *be != want {
+			t.Errorf("%s error = %+v, want %+v", tt.op, *be, want)
+		}
+		if !errors.Is(err, ErrBatchTooLarge) {
+			t.Errorf("%s error does not match ErrBatchTooLarge", tt.op)
+		}
+		if msg := err.Error(); !strings.Contains(msg, tt.op) || !strings.Contains(msg, "read=2 write=5 capacity=8") {
+			t.Errorf("%s message %q lacks the operation or indices", tt.op, msg)
+		}
+	}
+}
+
+func TestErrorSentinels(t *testing.T) {
+	rb := Newbuffer(2)
+	rb.EnqueueE(1, 0, 0)
+	rb.EnqueueE(2, 0, 0)
+	if err := rb.EnqueueE(3, 0, 0); err != ErrFull {
+		t.Fatalf("EnqueueE on a full buffer = %v, want ErrFull", err)
+	}
+
+	ids, prices, qtys := make([]uint64, 2), make([]float64, 2), make([]uint32, 2)
+	rb.Close()
+	if err := rb.EnqueueE(3, 0, 0); err != ErrClosed {
+		t.Fatalf("EnqueueE on a closed buffer = %v, want ErrClosed", err)
+	}
+	if n, err := rb.DequeueBatchE(ids, prices, qtys); n != 2 || err != nil {
+		t.Fatalf("DequeueBatchE on a closed, full buffer = %d, %v, want 2, nil", n, err)
+	}
+	if _, err := rb.DequeueBatchE(ids, prices, qtys); err != ErrClosed {
+		t.Fatalf("DequeueBatchE on a closed, drained buffer = %v, want ErrClosed", err)
+	}
+}
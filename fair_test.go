@@ -0,0 +1,146 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// TestDequeueBatchFair runs four producers against four consumers that share
+// a fixed number of tickets. Every ID must come out exactly once, and since a
+// ticket can't be lost the way a CAS can, the consumers' shares must stay
+// within a tight band of an even split.
+func TestDequeueBatchFair(t *testing.T) {
+	const (
+		producers = 4
+		consumers = 4
+		perWorker = 8192
+		batch     = 8
+	)
+	rb := Newbuffer(64)
+	total := producers * perWorker
+
+	var pwg sync.WaitGroup
+	pwg.Add(producers)
+	for p := range producers {
+		go func() {
+			defer pwg.Done()
+			for id := uint64(p * perWorker); id < uint64((p+1)*perWorker); id++ {
+				for !rb.Enqueue(id, 0, 0) {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+
+	var tickets atomic.Int64
+	tickets.Store(int64(total / batch))
+	seen := make([][]uint64, consumers)
+	var cwg sync.WaitGroup
+	cwg.Add(consumers)
+	for c := range consumers {
+		go func() {
+			defer cwg.Done()
+			ids := make([]uint64, batch)
+			prices := make([]float64, batch)
+			qtys := make([]uint32, batch)
+			for tickets.Add(-1) >= 0 {
+				n := rb.DequeueBatchFair(ids, prices, qtys)
+				seen[c] = append(seen[c], ids[:n]...)
+				runtime.Gosched()
+			}
+		}()
+	}
+	pwg.Wait()
+	cwg.Wait()
+
+	count := make([]int, total)
+	for c, ids := range seen {
+		share := float64(len(ids)) / float64(total/consumers)
+		t.Logf("consumer %d: %d items (%.2f of an even share)", c, len(ids), share)
+		if share < 0.8 || share > 1.2 {
+			t.Errorf("consumer %d took %d items, want within 20%% of %d", c, len(ids), total/consumers)
+		}
+		for _, id := range ids {
+			if id >= uint64(total) {
+				t.Fatalf("dequeued unknown ID %d", id)
+			}
+			count[id]++
+		}
+	}
+	for id, n := range count {
+		if n != 1 {
+			t.Fatalf("ID %d dequeued %d times", id, n)
+		}
+	}
+	if !rb.IsEmpty() {
+		t.Fatalf("Len() = %d after draining, want 0", rb.Len())
+	}
+}
+
+// TestConsumerSkew runs four DequeueBatch consumers with WithRetryLimit(4)
+// against two producers. Each consumer yields after every batch it handles,
+// as a consumer doing real work would be descheduled. The shares are looser
+// than with DequeueBatchFair, since a consumer can still lose races, but no
+// consumer may be starved: each must take between half and twice an even
+// share.
+func TestConsumerSkew(t *testing.T) {
+	const (
+		producers = 2
+		consumers = 4
+		perWorker = 16_384
+		batch     = 8
+	)
+	rb := NewbufferOpts(256, WithRetryLimit(4), WithMetrics(true))
+	total := producers * perWorker
+
+	var pwg sync.WaitGroup
+	pwg.Add(producers)
+	for p := range producers {
+		go func() {
+			defer pwg.Done()
+			ids := make([]uint64, batch)
+			prices := make([]float64, batch)
+			qtys := make([]uint32, batch)
+			for next := uint64(p * perWorker); next < uint64((p+1)*perWorker); next += batch {
+				for i := range ids {
+					ids[i] = next + uint64(i)
+				}
+				for rb.EnqueueBatch(ids, prices, qtys) == 0 {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+
+	var consumed atomic.Int64
+	taken := make([]int, consumers)
+	var cwg sync.WaitGroup
+	cwg.Add(consumers)
+	for c := range consumers {
+		go func() {
+			defer cwg.Done()
+			ids := make([]uint64, batch)
+			prices := make([]float64, batch)
+			qtys := make([]uint32, batch)
+			for consumed.Load() < int64(total) {
+				if n := rb.DequeueBatch(ids, prices, qtys); n > 0 {
+					taken[c] += int(n)
+					consumed.Add(int64(n))
+				}
+				runtime.Gosched()
+			}
+		}()
+	}
+	pwg.Wait()
+	cwg.Wait()
+
+	fair := float64(total) / consumers
+	for c, n := range taken {
+		if share := float64(n) / fair; share < 0.5 || share > 2 {
+			t.Errorf("consumer %d took %d items, %.2fx an even share", c, n, share)
+		}
+	}
+	t.Logf("shares %v of %d, %d lost read CAS races", taken, total, rb.Stats().ReadCASFailures)
+}
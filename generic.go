@@ -0,0 +1,201 @@
+package main
+
+import "sync/atomic"
+
+// RingBufferG is the same lock-free MPMC ring as RingBuffer, carrying any
+// payload type T instead of the Order columns. Each slot holds a whole T, so
+// there is one copy per item in and out; RingBuffer's column layout remains
+// the faster choice for Order itself.
+type RingBufferG[T any] struct {
+	capacity uint64
+	mask     uint64
+	_        [CacheLineSize]byte
+
+	writeIndex uint64
+	_          [CacheLineSize - 8]byte
+
+	readIndex uint64
+	_         [CacheLineSize - 8]byte
+
+	cycleState []cycle
+	items      []T
+}
+
+// NewbufferG returns a buffer of the given capacity, which must be a power of
+// two.
+func NewbufferG[T any](capacity uint64) *RingBufferG[T] {
+	checkCapacity("NewbufferG", capacity)
+
+	buffer := &RingBufferG[T]{
+		capacity:   capacity,
+		mask:       capacity - 1,
+		cycleState: make([]cycle, capacity),
+		items:      make([]T, capacity),
+	}
+	initCycleState(buffer.cycleState)
+	return buffer
+}
+
+func (rb *RingBufferG[T]) Enqueue(v T) bool {
+	var head uint64
+	var offset uint64
+
+	for {
+		head = atomic.LoadUint64(&rb.writeIndex)
+		offset = head & rb.mask
+		diff := cycleDiff(loadCycle(&rb.cycleState[offset]), head)
+
+		if diff == 0 {
+			if atomic.CompareAndSwapUint64(&rb.writeIndex, head, head+1) {
+				break
+			}
+		} else if diff < 0 {
+			return false
+		}
+	}
+
+	rb.items[offset] = v
+	storeCycle(&rb.cycleState[offset], head+1)
+	return true
+}
+
+func (rb *RingBufferG[T]) Dequeue() (T, bool) {
+	var v T
+	ok := rb.DequeueInto(&v)
+	return v, ok
+}
+
+// DequeueInto dequeues one item into *dst and reports whether there was one;
+// *dst is left alone when the buffer is empty. A consumer can reuse a single
+// destination across calls, so a loop over DequeueInto doesn't allocate.
+func (rb *RingBufferG[T]) DequeueInto(dst *T) bool {
+	var tail uint64
+	var offset uint64
+
+	for {
+		tail = atomic.LoadUint64(&rb.readIndex)
+		offset = tail & rb.mask
+		diff := cycleDiff(loadCycle(&rb.cycleState[offset]), tail+1)
+
+		if diff == 0 {
+			if atomic.CompareAndSwapUint64(&rb.readIndex, tail, tail+1) {
+				break
+			}
+		} else if diff < 0 {
+			return false
+		}
+	}
+
+	*dst = rb.items[offset]
+	rb.items[offset] = zero[T]()
+	storeCycle(&rb.cycleState[offset], tail+rb.capacity)
+	return true
+}
+
+// zero is cleared into a slot after its value is read, so the buffer doesn't
+// keep dequeued values reachable.
+func zero[T any]() T {
+	var v T
+	return v
+}
+
+// EnqueueBatch enqueues all of items or none of them and returns how many
+// were written. Like RingBuffer.EnqueueBatch it panics with a *BufferError
+// wrapping ErrBatchTooLarge if items is longer than the buffer.
+func (rb *RingBufferG[T]) EnqueueBatch(items []T) uint64 {
+	count := uint64(len(items))
+	if count == 0 {
+		return 0
+	}
+	if count > rb.capacity {
+		panic(rb.newError("EnqueueBatch", count, ErrBatchTooLarge))
+	}
+
+	for {
+		head := atomic.LoadUint64(&rb.writeIndex)
+		n, stale := rb.claimable(head, count)
+		if stale {
+			continue
+		}
+		if n < count {
+			return 0
+		}
+
+		if atomic.CompareAndSwapUint64(&rb.writeIndex, head, head+count) {
+			for i := uint64(0); i < count; i++ {
+				offset := (head + i) & rb.mask
+				rb.items[offset] = items[i]
+				storeCycle(&rb.cycleState[offset], head+i+1)
+			}
+			return count
+		}
+	}
+}
+
+// DequeueBatch fills dst completely or not at all and returns how many items
+// were read. It panics like EnqueueBatch if dst is longer than the buffer.
+func (rb *RingBufferG[T]) DequeueBatch(dst []T) uint64 {
+	limit := uint64(len(dst))
+	if limit == 0 {
+		return 0
+	}
+	if limit > rb.capacity {
+		panic(rb.newError("DequeueBatch", limit, ErrBatchTooLarge))
+	}
+
+	for {
+		tail := atomic.LoadUint64(&rb.readIndex)
+		n, stale := rb.readable(tail, limit)
+		if stale {
+			continue
+		}
+		if n < limit {
+			return 0
+		}
+
+		if atomic.CompareAndSwapUint64(&rb.readIndex, tail, tail+limit) {
+			for i := uint64(0); i < limit; i++ {
+				offset := (tail + i) & rb.mask
+				dst[i] = rb.items[offset]
+				rb.items[offset] = zero[T]()
+				storeCycle(&rb.cycleState[offset], tail+i+rb.capacity)
+			}
+			return limit
+		}
+	}
+}
+
+// claimable and readable are RingBuffer's, for RingBufferG: the length of the
+// run of free or published slots from a sequence, up to limit, and whether
+// the run ended because the sequence was already stale.
+func (rb *RingBufferG[T]) claimable(head, limit uint64) (count uint64, stale bool) {
+	for i := uint64(0); i < limit; i++ {
+		seq := head + i
+		if diff := cycleDiff(loadCycle(&rb.cycleState[seq&rb.mask]), seq); diff != 0 {
+			return i, diff > 0
+		}
+	}
+	return limit, false
+}
+
+func (rb *RingBufferG[T]) readable(tail, limit uint64) (count uint64, stale bool) {
+	for i := uint64(0); i < limit; i++ {
+		seq := tail + i
+		if diff := cycleDiff(loadCycle(&rb.cycleState[seq&rb.mask]), seq+1); diff != 0 {
+			return i, diff > 0
+		}
+	}
+	return limit, false
+}
+
+// newError is RingBuffer.newError for RingBufferG.
+func (rb *RingBufferG[T]) newError(op string, attempted uint64, err error) *BufferError {
+	return &BufferError{
+		Op:         op,
+		ReadIndex:  atomic.LoadUint64(&rb.readIndex),
+		WriteIndex: atomic.LoadUint64(&rb.writeIndex),
+		Capacity:   rb.capacity,
+		Attempted:  attempted,
+		Err:        err,
+	}
+}
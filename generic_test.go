@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRingBufferGBatchTooLarge(t *testing.T) {
+	rb := NewbufferG[int](4)
+	big := make([]int, 5)
+	if err := panicsWithin(func() { rb.EnqueueBatch(big) }); !errors.Is(err, ErrBatchTooLarge) {
+		t.Errorf("EnqueueBatch(5) on capacity 4: recovered %v, want ErrBatchTooLarge", err)
+	}
+	if err := panicsWithin(func() { rb.DequeueBatch(big) }); !errors.Is(err, ErrBatchTooLarge) {
+		t.Errorf("DequeueBatch(5) on capacity 4: recovered %v, want ErrBatchTooLarge", err)
+	}
+}
+
+func TestRingBufferGStaleIndex(t *testing.T) {
+	rb := NewbufferG[int](8)
+	rb.EnqueueBatch([]int{1, 2})
+	if n, stale := rb.claimable(0, 2); n != 0 || !stale {
+		t.Fatalf("claimable(0, 2) = %d, %v after the slots were taken, want 0, true", n, stale)
+	}
+	dst := make([]int, 2)
+	rb.DequeueBatch(dst)
+	if n, stale := rb.readable(0, 2); n != 0 || !stale {
+		t.Fatalf("readable(0, 2) = %d, %v after the slots were read, want 0, true", n, stale)
+	}
+}
+
+// TestRingBufferGBatchConcurrent moves disjoint ranges through four batch
+// producers and four batch consumers; every value must arrive exactly once.
+func TestRingBufferGBatchConcurrent(t *testing.T) {
+	const (
+		workers   = 4
+		perWorker = 20_000
+		batch     = 4
+	)
+	rb := NewbufferG[int](64)
+	total := workers * perWorker
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for p := range workers {
+		go func() {
+			defer wg.Done()
+			items := make([]int, batch)
+			for next := p * perWorker; next < (p+1)*perWorker; next += batch {
+				for i := range items {
+					items[i] = next + i
+				}
+				for rb.EnqueueBatch(items) == 0 {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+
+	seen := make([]atomic.Int32, total)
+	var consumed atomic.Int64
+	var consumers sync.WaitGroup
+	consumers.Add(workers)
+	for range workers {
+		go func() {
+			defer consumers.Done()
+			dst := make([]int, batch)
+			for consumed.Load() < int64(total) {
+				if rb.DequeueBatch(dst) == 0 {
+					runtime.Gosched()
+					continue
+				}
+				for _, v := range dst {
+					seen[v].Add(1)
+				}
+				consumed.Add(batch)
+			}
+		}()
+	}
+	wg.Wait()
+	consumers.Wait()
+
+	for v := range seen {
+		if n := seen[v].Load(); n != 1 {
+			t.Fatalf("value %d dequeued %d times", v, n)
+		}
+	}
+}
+
+func TestRingBufferGDequeueInto(t *testing.T) {
+	type quote struct {
+		Seq   uint64
+		Venue string
+		Bids  [4]float64
+	}
+	rb := NewbufferG[quote](8)
+	want := quote{Seq: 7, Venue: "XNAS", Bids: [4]float64{1, 2, 3, 4}}
+	rb.Enqueue(want)
+
+	var dst quote
+	if !rb.DequeueInto(&dst) || dst != want {
+		t.Fatalf("DequeueInto = %+v, want %+v", dst, want)
+	}
+	if rb.DequeueInto(&dst) || dst != want {
+		t.Fatalf("DequeueInto on an empty buffer reported an item or changed dst to %+v", dst)
+	}
+
+	allocs := testing.AllocsPerRun(1000, func() {
+		rb.Enqueue(want)
+		rb.DequeueInto(&dst)
+	})
+	if allocs != 0 {
+		t.Fatalf("Enqueue/DequeueInto allocated %.1f times per round trip, want 0", allocs)
+	}
+}
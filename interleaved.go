@@ -0,0 +1,99 @@
+package main
+
+import "sync/atomic"
+
+// slot keeps an item's cycle stamp next to its payload, so a claim and the
+// data it guards share a cache line.
+type slot struct {
+	seq   cycle
+	id    uint64
+	price float64
+	qty   uint32
+}
+
+// InterleavedBuffer is the array-of-structs counterpart of RingBuffer: the
+// same cycle-state algorithm, but each slot's stamp and payload live together
+// instead of in four parallel arrays. It exists to compare the two layouts;
+// see the Interleaved run in the benchmark.
+type InterleavedBuffer struct {
+	capacity uint64
+	mask     uint64
+	_        [CacheLineSize]byte
+
+	writeIndex uint64
+	_          [CacheLineSize - 8]byte
+
+	readIndex uint64
+	_         [CacheLineSize - 8]byte
+
+	slots []slot
+}
+
+func NewInterleavedBuffer(capacity uint64) *InterleavedBuffer {
+	checkCapacity("NewInterleavedBuffer", capacity)
+	rb := &InterleavedBuffer{
+		capacity: capacity,
+		mask:     capacity - 1,
+		slots:    make([]slot, capacity),
+	}
+	for i := range rb.slots {
+		rb.slots[i].seq = cycle(i)
+	}
+	return rb
+}
+
+func (rb *InterleavedBuffer) EnqueueBatch(ids []uint64, prices []float64, qtys []uint32) uint64 {
+	count := uint64(len(ids))
+	if count == 0 || count > rb.capacity {
+		return 0
+	}
+
+	for {
+		head := atomic.LoadUint64(&rb.writeIndex)
+		for i := uint64(0); i < count; i++ {
+			seq := head + i
+			if cycleDiff(loadCycle(&rb.slots[seq&rb.mask].seq), seq) != 0 {
+				return 0
+			}
+		}
+
+		if atomic.CompareAndSwapUint64(&rb.writeIndex, head, head+count) {
+			for i := uint64(0); i < count; i++ {
+				s := &rb.slots[(head+i)&rb.mask]
+				s.id = ids[i]
+				s.price = prices[i]
+				s.qty = qtys[i]
+				storeCycle(&s.seq, head+i+1)
+			}
+			return count
+		}
+	}
+}
+
+func (rb *InterleavedBuffer) DequeueBatch(ids []uint64, prices []float64, qtys []uint32) uint64 {
+	limit := uint64(len(ids))
+	if limit == 0 || limit > rb.capacity {
+		return 0
+	}
+
+	for {
+		tail := atomic.LoadUint64(&rb.readIndex)
+		for i := uint64(0); i < limit; i++ {
+			seq := tail + i
+			if cycleDiff(loadCycle(&rb.slots[seq&rb.mask].seq), seq+1) != 0 {
+				return 0
+			}
+		}
+
+		if atomic.CompareAndSwapUint64(&rb.readIndex, tail, tail+limit) {
+			for i := uint64(0); i < limit; i++ {
+				s := &rb.slots[(tail+i)&rb.mask]
+				ids[i] = s.id
+				prices[i] = s.price
+				qtys[i] = s.qty
+				storeCycle(&s.seq, tail+i+rb.capacity)
+			}
+			return limit
+		}
+	}
+}
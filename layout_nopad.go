@@ -0,0 +1,38 @@
+//go:build nopad
+
+package main
+
+// Layout without the cache-line padding, so writeIndex and readIndex share a
+// line with each other and with the read-only header. Only used to measure
+// what the padding buys: build the benchmark with -tags nopad and compare.
+const Padding = "unpadded"
+
+// paddedLine is 0: the indices share a line whatever its size.
+const paddedLine = 0
+
+type RingBuffer struct {
+	capacity uint64
+	mask     uint64
+
+	writeIndex uint64
+	readIndex  uint64
+
+	cycleState []cycle
+	ids        []uint64
+	prices     []float64
+	qtys       []uint32
+
+	wait       WaitStrategy
+	spinWait   WaitStrategy
+	policy     Policy
+	dropped    uint64
+	closed     uint32
+	stats      *bufferStats
+	retryLimit int
+	scratch    *scratchPool
+	done       *doneSignal
+	batchDedup bool
+	oplog      *operationLog
+	backing    BackingProvider
+	spill      *spillover
+}
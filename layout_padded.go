@@ -0,0 +1,39 @@
+//go:build !nopad
+
+package main
+
+const Padding = "padded"
+
+// paddedLine is the widest cache line the layout keeps the indices apart on.
+const paddedLine = CacheLineSize
+
+type RingBuffer struct {
+	capacity uint64
+	mask     uint64
+	_        [CacheLineSize]byte
+
+	writeIndex uint64
+	_          [CacheLineSize - 8]byte
+
+	readIndex uint64
+	_         [CacheLineSize - 8]byte
+
+	cycleState []cycle
+	ids        []uint64
+	prices     []float64
+	qtys       []uint32
+
+	wait       WaitStrategy
+	spinWait   WaitStrategy
+	policy     Policy
+	dropped    uint64
+	closed     uint32
+	stats      *bufferStats
+	retryLimit int
+	scratch    *scratchPool
+	done       *doneSignal
+	batchDedup bool
+	oplog      *operationLog
+	backing    BackingProvider
+	spill      *spillover
+}
@@ -1,20 +1,52 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"os"
+	"os/signal"
 	"runtime"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
 const (
+	BufferSize = 1024 * 16
+
+	LatencyEvents = 1_000_000
+	ShardEvents   = 2_000_000
+
+	SlowProducerEvents = 2_000
+	SlowProducerDelay  = 200 * time.Microsecond
+
+	AdaptiveEvents    = 1_000_000
+	AdaptivePhase     = 2 * time.Millisecond
+	AdaptiveSlowDelay = 50 * time.Microsecond
+)
+
+// The workload shape, overridable with -events, -producers, -consumers and
+// -batch so topologies can be compared without editing the source.
+var (
 	TotalEvents  = 10_000_000
-	BufferSize   = 1024 * 16
 	NumProducers = 4
 	NumConsumers = 4
 	BatchSize    = 16
 )
 
+// pinWorkers, set with -pin, pins each worker of the channel and batch
+// throughput runs to its own CPU, round robin, for steadier numbers.
+var (
+	pinWorkers bool
+	pinFailed  sync.Once
+)
+
+// extraBenchmarks are optional runs registered by build-tagged files. Each
+// gets the run's context, which is cancelled on SIGINT or SIGTERM.
+var extraBenchmarks []func(ctx context.Context)
+
 type Order struct {
 	ID    uint64
 	Price float64
@@ -22,19 +54,104 @@ type Order struct {
 }
 
 func main() {
+	flag.IntVar(&TotalEvents, "events", TotalEvents, "events per throughput run")
+	flag.IntVar(&NumProducers, "producers", NumProducers, "producer goroutines")
+	flag.IntVar(&NumConsumers, "consumers", NumConsumers, "consumer goroutines")
+	flag.IntVar(&BatchSize, "batch", BatchSize, "items per batch operation")
+	flag.BoolVar(&pinWorkers, "pin", false, "pin channel and batch benchmark workers to CPUs (Linux only)")
+	flag.Parse()
+
 	runtime.GOMAXPROCS(runtime.NumCPU())
+
+	if err := checkWorkload(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
 	fmt.Printf("CPU Cores: %d\n", runtime.NumCPU())
 	fmt.Printf("Workload:  %d events\n", TotalEvents)
 	fmt.Printf("Layout:    %d Producers / %d Consumers\n", NumProducers, NumConsumers)
 	fmt.Printf("BatchSize: %d\n", BatchSize)
+	fmt.Printf("Padding:   %s\n", Padding)
+	fmt.Printf("Pinned:    %v\n", pinWorkers)
 	fmt.Println("---------------------------------------------------------")
 
-	runChannelBenchmark()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	printSummary(runAll(ctx))
+}
+
+// runAll runs every benchmark in turn and returns the throughput results for
+// the summary. Once ctx is cancelled the run in progress stops its producers,
+// lets its consumers drain what was produced and reports partial numbers, and
+// the remaining runs are skipped. Workers check ctx.Err() between items, which
+// is a single atomic load, or wait on the buffer through EnqueueContext.
+func runAll(ctx context.Context) []result {
+	results := []result{{"Go Channel", runChannelBenchmark(ctx)}}
+	if ctx.Err() == nil {
+		results = append(results, result{"RingBuffer", runBatchBenchmark(ctx, "RingBuffer", Newbuffer(BufferSize))})
+	}
+	if ctx.Err() == nil {
+		results = append(results, result{"Interleaved", runBatchBenchmark(ctx, "Interleaved", NewInterleavedBuffer(BufferSize))})
+	}
+	if ctx.Err() == nil {
+		results = append(results, result{"RingBufferG[Order]", runGenericBenchmark(ctx)})
+	}
+	for _, q := range comparisonQueues {
+		if ctx.Err() != nil {
+			break
+		}
+		results = append(results, result{q.name, runComparisonBenchmark(ctx, q)})
+	}
+
+	runs := []func(context.Context){runProducerOnlyBenchmark, runConsumerOnlyBenchmark, runEnqueueLatencyBenchmark, runShardedBenchmark, runSPSCBenchmark, runFanBenchmark, runBatcherBenchmark, runFalseSharingBenchmark, runSlowProducerBenchmark, runConsumerSkewBenchmark, runAdaptiveBatchBenchmark}
+	for _, run := range append(runs, extraBenchmarks...) {
+		if ctx.Err() != nil {
+			break
+		}
+		run(ctx)
+	}
+
+	if ctx.Err() != nil {
+		fmt.Println("Interrupted, remaining runs skipped")
+	}
+	return results
+}
+
+// checkWorkload rejects shapes the runs can't split evenly. Every producer
+// and consumer handles a whole number of batches, both of the total and of one
+// buffer's worth in the producer- and consumer-only runs, so a remainder would
+// leave consumers waiting for items nobody produces.
+func checkWorkload() error {
+	if NumProducers < 1 || NumConsumers < 1 || BatchSize < 1 || TotalEvents < 1 {
+		return fmt.Errorf("-events, -producers, -consumers and -batch must be positive")
+	}
+	if BatchSize > BufferSize {
+		return fmt.Errorf("-batch=%d exceeds the buffer size %d", BatchSize, BufferSize)
+	}
+	for _, n := range []int{NumProducers, NumConsumers} {
+		if TotalEvents%(n*BatchSize) != 0 || BufferSize%(n*BatchSize) != 0 {
+			return fmt.Errorf("-events=%d and buffer size %d must both be multiples of %d workers x -batch=%d",
+				TotalEvents, BufferSize, n, BatchSize)
+		}
+	}
+	return nil
+}
 
-	runRingBufferBenchmark()
+// pinWorker pins the calling goroutine to a CPU chosen from its worker
+// number when -pin is set. Producers are numbered first, then consumers, so
+// with enough cores every worker gets its own. A failure is reported once and
+// the run continues unpinned.
+func pinWorker(worker int) {
+	if !pinWorkers {
+		return
+	}
+	if err := PinToCPU(worker % runtime.NumCPU()); err != nil {
+		pinFailed.Do(func() { fmt.Fprintf(os.Stderr, "pinning failed, running unpinned: %v\n", err) })
+	}
 }
 
-func runChannelBenchmark() {
+func runChannelBenchmark(ctx context.Context) float64 {
 	fmt.Print("Running Go Channel Benchmark...  ")
 
 	ch := make(chan Order, BufferSize)
@@ -42,14 +159,18 @@ func runChannelBenchmark() {
 
 	start := time.Now()
 
+	var produced atomic.Uint64
 	msgsPerProducer := TotalEvents / NumProducers
 	wg.Add(NumProducers)
 	for p := 0; p < NumProducers; p++ {
 		go func() {
 			defer wg.Done()
-			for i := 0; i < msgsPerProducer; i++ {
+			pinWorker(p)
+			i := 0
+			for ; i < msgsPerProducer && ctx.Err() == nil; i++ {
 				ch <- Order{ID: uint64(i), Price: 100.0, Qty: 1}
 			}
+			produced.Add(uint64(i))
 		}()
 	}
 
@@ -58,6 +179,7 @@ func runChannelBenchmark() {
 	for c := 0; c < NumConsumers; c++ {
 		go func() {
 			defer consumerWg.Done()
+			pinWorker(NumProducers + c)
 			for range ch {
 			}
 		}()
@@ -68,16 +190,23 @@ func runChannelBenchmark() {
 	consumerWg.Wait()
 
 	duration := time.Since(start)
-	ops := float64(TotalEvents) / duration.Seconds()
-	fmt.Printf("Done in %v\n", duration)
+	ops := float64(produced.Load()) / duration.Seconds()
+	reportProgress(ctx, duration, produced.Load())
 	fmt.Printf(">> Channel Throughput:    %.0f ops/sec\n", ops)
 	fmt.Println("---------------------------------------------------------")
+	return ops
 }
 
-func runRingBufferBenchmark() {
-	fmt.Print("Running RingBuffer Batch Benchmark...  ")
+// batchQueue is implemented by the ring buffer layouts that the batch
+// benchmark can drive.
+type batchQueue interface {
+	EnqueueBatch(ids []uint64, prices []float64, qtys []uint32) uint64
+	DequeueBatch(ids []uint64, prices []float64, qtys []uint32) uint64
+}
+
+func runBatchBenchmark(ctx context.Context, name string, rb batchQueue) float64 {
+	fmt.Printf("Running %s Batch Benchmark...  ", name)
 
-	rb := Newbuffer(BufferSize)
 	var wg sync.WaitGroup
 
 	start := time.Now()
@@ -87,6 +216,7 @@ func runRingBufferBenchmark() {
 	for p := 0; p < NumProducers; p++ {
 		go func() {
 			defer wg.Done()
+			pinWorker(p)
 			
 			ids := make([]uint64, BatchSize)
 			prices := make([]float64, BatchSize)
@@ -100,7 +230,7 @@ func runRingBufferBenchmark() {
 
 			loops := msgsPerProducer / BatchSize
 			
-			for i := 0; i < loops; i++ {
+			for i := 0; i < loops && ctx.Err() == nil; i++ {
 				for rb.EnqueueBatch(ids, prices, qtys) == 0 {
 					runtime.Gosched()
 				}
@@ -108,6 +238,9 @@ func runRingBufferBenchmark() {
 		}()
 	}
 
+	var producersDone atomic.Bool
+	var consumed atomic.Uint64
+
 	msgsPerConsumer := TotalEvents / NumConsumers
 	consumerWg := sync.WaitGroup{}
 	consumerWg.Add(NumConsumers)
@@ -115,6 +248,7 @@ func runRingBufferBenchmark() {
 	for c := 0; c < NumConsumers; c++ {
 		go func() {
 			defer consumerWg.Done()
+			pinWorker(NumProducers + c)
 			
 			ids := make([]uint64, BatchSize)
 			prices := make([]float64, BatchSize)
@@ -125,19 +259,716 @@ func runRingBufferBenchmark() {
 				n := rb.DequeueBatch(ids, prices, qtys)
 				if n > 0 {
 					processed += int(n)
+				} else if producersDone.Load() {
+					break
 				} else {
 					runtime.Gosched()
 				}
 			}
+			consumed.Add(uint64(processed))
 		}()
 	}
 
 	wg.Wait()
+	if ctx.Err() != nil {
+		producersDone.Store(true)
+	}
 	consumerWg.Wait()
 
 	duration := time.Since(start)
-	ops := float64(TotalEvents) / duration.Seconds()
+	ops := float64(consumed.Load()) / duration.Seconds()
+	reportProgress(ctx, duration, consumed.Load())
+	if r, ok := rb.(*RingBuffer); ok && ctx.Err() != nil {
+		fmt.Printf(">> Buffer state: %v laps=%d\n", r.Snapshot(), r.Laps())
+	}
+	fmt.Printf(">> %s Throughput: %.0f ops/sec\n", name, ops)
+	fmt.Println("---------------------------------------------------------")
+	return ops
+}
+func makeBatch() ([]uint64, []float64, []uint32) {
+	ids := make([]uint64, BatchSize)
+	prices := make([]float64, BatchSize)
+	qtys := make([]uint32, BatchSize)
+
+	for k := 0; k < BatchSize; k++ {
+		ids[k] = uint64(k)
+		prices[k] = 100.0
+		qtys[k] = 1
+	}
+	return ids, prices, qtys
+}
+
+// runProducerOnlyBenchmark times only the enqueue side: producers fill an
+// empty buffer to capacity, the clock stops, and the buffer is drained
+// untimed before the next round. It runs as many rounds as it takes to cover
+// TotalEvents, so at least one even when -events is below the buffer size.
+func runProducerOnlyBenchmark(ctx context.Context) {
+	fmt.Print("Running RingBuffer Producer-Only Benchmark...  ")
+
+	rb := Newbuffer(BufferSize)
+	ids, prices, qtys := makeBatch()
+	loops := BufferSize / NumProducers / BatchSize
+	rounds := (TotalEvents + BufferSize - 1) / BufferSize
+
+	var elapsed time.Duration
+	r := 0
+	for ; r < rounds && ctx.Err() == nil; r++ {
+		var wg sync.WaitGroup
+		wg.Add(NumProducers)
+
+		start := time.Now()
+		for p := 0; p < NumProducers; p++ {
+			go func() {
+				defer wg.Done()
+				for i := 0; i < loops; i++ {
+					for rb.EnqueueBatch(ids, prices, qtys) == 0 {
+						runtime.Gosched()
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		elapsed += time.Since(start)
+
+		for rb.DequeueBatch(ids, prices, qtys) > 0 {
+		}
+	}
+
+	ops := float64(r*BufferSize) / elapsed.Seconds()
+	reportProgress(ctx, elapsed, uint64(r*BufferSize))
+	fmt.Printf(">> Enqueue Throughput:    %.0f ops/sec\n", ops)
+	fmt.Println("---------------------------------------------------------")
+}
+
+// runConsumerOnlyBenchmark times only the dequeue side: the buffer is filled
+// to capacity untimed, then consumers drain it against the clock. Rounds are
+// counted as in runProducerOnlyBenchmark.
+func runConsumerOnlyBenchmark(ctx context.Context) {
+	fmt.Print("Running RingBuffer Consumer-Only Benchmark...  ")
+
+	rb := Newbuffer(BufferSize)
+	ids, prices, qtys := makeBatch()
+	loops := BufferSize / NumConsumers / BatchSize
+	rounds := (TotalEvents + BufferSize - 1) / BufferSize
+
+	var elapsed time.Duration
+	r := 0
+	for ; r < rounds && ctx.Err() == nil; r++ {
+		for rb.EnqueueBatch(ids, prices, qtys) > 0 {
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(NumConsumers)
+
+		start := time.Now()
+		for c := 0; c < NumConsumers; c++ {
+			go func() {
+				defer wg.Done()
+				ids, prices, qtys := makeBatch()
+				for i := 0; i < loops; i++ {
+					for rb.DequeueBatch(ids, prices, qtys) == 0 {
+						runtime.Gosched()
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		elapsed += time.Since(start)
+	}
+
+	ops := float64(r*BufferSize) / elapsed.Seconds()
+	reportProgress(ctx, elapsed, uint64(r*BufferSize))
+	fmt.Printf(">> Dequeue Throughput:    %.0f ops/sec\n", ops)
+	fmt.Println("---------------------------------------------------------")
+}
+
+// runEnqueueLatencyBenchmark characterises producer starvation. Every
+// producer times each successful single-item Enqueue, so a producer that keeps
+// losing the writeIndex CAS to its peers shows up as a large worst case even
+// though the buffer as a whole keeps making progress.
+func runEnqueueLatencyBenchmark(ctx context.Context) {
+	fmt.Println("Running RingBuffer Enqueue Latency Benchmark...")
+
+	rb := Newbuffer(BufferSize)
+	var stop atomic.Bool
+
+	var consumerWg sync.WaitGroup
+	consumerWg.Add(NumConsumers)
+	for c := 0; c < NumConsumers; c++ {
+		go func() {
+			defer consumerWg.Done()
+			var id uint64
+			var price float64
+			var qty uint32
+			for !stop.Load() {
+				if !rb.Dequeue(&id, &price, &qty) {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+
+	msgsPerProducer := LatencyEvents / NumProducers
+	worst := make([]time.Duration, NumProducers)
+	total := make([]time.Duration, NumProducers)
+	count := make([]int, NumProducers)
+
+	var wg sync.WaitGroup
+	wg.Add(NumProducers)
+	for p := 0; p < NumProducers; p++ {
+		go func() {
+			defer wg.Done()
+			for i := 0; i < msgsPerProducer && ctx.Err() == nil; i++ {
+				count[p]++
+				for {
+					start := time.Now()
+					ok := rb.Enqueue(uint64(i), 100.0, 1)
+					if ok {
+						d := time.Since(start)
+						total[p] += d
+						worst[p] = max(worst[p], d)
+						break
+					}
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	stop.Store(true)
+	consumerWg.Wait()
+
+	for p := 0; p < NumProducers; p++ {
+		mean := total[p] / time.Duration(max(count[p], 1))
+		fmt.Printf(">> Producer %d: mean %v, worst %v\n", p, mean, worst[p])
+	}
+	fmt.Println("---------------------------------------------------------")
+}
+
+// runFalseSharingBenchmark has two goroutines hammer counters 8, 64 and 128
+// bytes apart. Once the distance reaches the hardware's line size the counters
+// stop sharing a line and the time drops, which shows whether CacheLineSize is
+// large enough for this CPU. It needs at least two cores to show anything.
+func runFalseSharingBenchmark(ctx context.Context) {
+	fmt.Println("Running False Sharing Benchmark...")
+
+	const ops = 20_000_000
+	for _, dist := range []int{8, 64, 128} {
+		if ctx.Err() != nil {
+			break
+		}
+
+		// 256 bytes falls in a 256-byte size class, so the block starts on a
+		// line boundary for both 64- and 128-byte lines.
+		counters := make([]uint64, 32)
+		var wg sync.WaitGroup
+		wg.Add(2)
+		start := time.Now()
+		for _, c := range []*uint64{&counters[0], &counters[dist/8]} {
+			go func() {
+				defer wg.Done()
+				for i := 0; i < ops; i++ {
+					atomic.AddUint64(c, 1)
+				}
+			}()
+		}
+		wg.Wait()
+		fmt.Printf(">> %3d bytes apart: %v\n", dist, time.Since(start))
+	}
+	fmt.Printf(">> CacheLineSize: %d\n", CacheLineSize)
+	fmt.Println("---------------------------------------------------------")
+}
+
+// runSlowProducerBenchmark shows what the spin wait costs while consumers wait
+// on a producer that claims a slot and takes its time to publish it. One
+// producer publishes SlowProducerEvents items through Claim/Publish, pausing
+// before each publish, while the consumers are already parked on those slots
+// in DequeueBatchFair. It reports CPU used and the mean time from publish to
+// dequeue, first with consumers that only ever spin and yield, then with
+// DefaultSpinWait.
+func runSlowProducerBenchmark(ctx context.Context) {
+	fmt.Println("Running Slow Producer Benchmark...")
+
+	waits := []struct {
+		name string
+		ws   WaitStrategy
+	}{
+		{"spin+yield", Backoff(activeSpin, -1, 0)},
+		{"DefaultSpinWait", DefaultSpinWait},
+	}
+	for _, w := range waits {
+		if ctx.Err() != nil {
+			break
+		}
+
+		rb := NewbufferOpts(BufferSize, WithSpinWait(w.ws))
+		var latency atomic.Int64
+		cpuBefore, cpuOK := cpuTime()
+		start := time.Now()
+
+		var consumerWg sync.WaitGroup
+		consumerWg.Add(NumConsumers)
+		for c := 0; c < NumConsumers; c++ {
+			go func() {
+				defer consumerWg.Done()
+				ids, prices, qtys := make([]uint64, 1), make([]float64, 1), make([]uint32, 1)
+				for i := 0; i < SlowProducerEvents/NumConsumers; i++ {
+					rb.DequeueBatchFair(ids, prices, qtys)
+					latency.Add(time.Now().UnixNano() - int64(ids[0]))
+				}
+			}()
+		}
+
+		for i := 0; i < SlowProducerEvents; i++ {
+			seq, _ := rb.Claim()
+			time.Sleep(SlowProducerDelay)
+			rb.SetID(seq, uint64(time.Now().UnixNano()))
+			rb.Publish(seq)
+		}
+		consumerWg.Wait()
+
+		elapsed := time.Since(start)
+		cpu := "n/a"
+		if cpuAfter, ok := cpuTime(); cpuOK && ok {
+			cpu = (cpuAfter - cpuBefore).Round(time.Millisecond).String()
+		}
+		fmt.Printf(">> %-16s wall %v, CPU %s, mean publish-to-dequeue %v\n",
+			w.name, elapsed.Round(time.Millisecond), cpu, time.Duration(latency.Load()/SlowProducerEvents))
+	}
+	fmt.Println("---------------------------------------------------------")
+}
+
+// runConsumerSkewBenchmark measures how evenly DequeueBatch spreads items
+// across competing consumers, with unlimited CAS retries and with
+// WithRetryLimit. Each consumer counts what it took; the run prints the
+// smallest and largest share and how many CAS races were lost.
+func runConsumerSkewBenchmark(ctx context.Context) {
+	fmt.Println("Running Consumer Skew Benchmark...")
+
+	loops := ShardEvents / NumProducers / BatchSize
+	total := uint64(loops * NumProducers * BatchSize)
+	configs := []struct {
+		name string
+		opts []Option
+	}{
+		{"unlimited", nil},
+		{"WithRetryLimit(4)", []Option{WithRetryLimit(4)}},
+	}
+	for _, cfg := range configs {
+		if ctx.Err() != nil {
+			break
+		}
+
+		rb := NewbufferOpts(BufferSize, append(cfg.opts, WithMetrics(true))...)
+		var consumed atomic.Uint64
+		shares := make([]uint64, NumConsumers)
+		start := time.Now()
+
+		var wg sync.WaitGroup
+		wg.Add(NumProducers + NumConsumers)
+		for p := 0; p < NumProducers; p++ {
+			go func() {
+				defer wg.Done()
+				ids, prices, qtys := makeBatch()
+				for i := 0; i < loops && ctx.Err() == nil; i++ {
+					for rb.EnqueueBatch(ids, prices, qtys) == 0 && ctx.Err() == nil {
+						runtime.Gosched()
+					}
+				}
+			}()
+		}
+		for c := 0; c < NumConsumers; c++ {
+			go func() {
+				defer wg.Done()
+				ids, prices, qtys := makeBatch()
+				for consumed.Load() < total && ctx.Err() == nil {
+					n := rb.DequeueBatch(ids, prices, qtys)
+					if n == 0 {
+						runtime.Gosched()
+						continue
+					}
+					shares[c] += n
+					consumed.Add(n)
+				}
+			}()
+		}
+		wg.Wait()
+
+		elapsed := time.Since(start)
+		lo, hi := shares[0], shares[0]
+		for _, n := range shares[1:] {
+			lo, hi = min(lo, n), max(hi, n)
+		}
+		fair := float64(consumed.Load()) / float64(NumConsumers)
+		fmt.Printf(">> %-18s %.0f ops/sec, per-consumer share min %.2fx max %.2fx of fair, lost CAS races %d\n",
+			cfg.name, float64(consumed.Load())/elapsed.Seconds(), float64(lo)/fair, float64(hi)/fair, rb.Stats().DequeueRetries)
+	}
+	fmt.Println("---------------------------------------------------------")
+}
+
+type singleItemQueue interface {
+	Enqueue(id uint64, price float64, qty uint32) bool
+	Dequeue(id *uint64, price *float64, qty *uint32) bool
+}
+
+// runShardedBenchmark compares one RingBuffer with a ShardedBuffer of the
+// same total capacity as the number of producers grows. It uses single-item
+// operations, so every item costs a writeIndex CAS and contention on that
+// line dominates.
+func runShardedBenchmark(ctx context.Context) {
+	fmt.Println("Running Sharded vs Single RingBuffer Benchmark...")
+
+	const shards = 8
+	for _, producers := range []int{1, 4, 8, 16} {
+		if ctx.Err() != nil {
+			break
+		}
+		single := runSingleItemBenchmark(ctx, Newbuffer(BufferSize), producers, NumConsumers)
+		sharded := runSingleItemBenchmark(ctx, NewShardedBuffer(shards, BufferSize/shards), producers, NumConsumers)
+		fmt.Printf(">> %2d producers: RingBuffer %12.0f ops/sec, Sharded(%d) %12.0f ops/sec\n",
+			producers, single, shards, sharded)
+	}
+	fmt.Println("---------------------------------------------------------")
+}
+
+// runSPSCBenchmark runs one producer and one consumer through the MPMC
+// RingBuffer and through SPSCBuffer, to show what dropping the CAS and the
+// cycle states buys when the topology allows it.
+func runSPSCBenchmark(ctx context.Context) {
+	fmt.Println("Running SPSC vs MPMC Benchmark (1P/1C)...")
+
+	mpmc := runSingleItemBenchmark(ctx, Newbuffer(BufferSize), 1, 1)
+	if ctx.Err() != nil {
+		return
+	}
+	spsc := runSingleItemBenchmark(ctx, NewSPSCBuffer(BufferSize), 1, 1)
+	fmt.Printf(">> RingBuffer %12.0f ops/sec, SPSCBuffer %12.0f ops/sec\n", mpmc, spsc)
+	fmt.Println("---------------------------------------------------------")
+}
+
+// runBatcherBenchmark pushes single items from every producer, first
+// straight through RingBuffer.Enqueue and then through a Batcher per
+// goroutine, and reports throughput and lost writeIndex CAS races per item.
+func runBatcherBenchmark(ctx context.Context) {
+	fmt.Println("Running Batcher Benchmark...")
+
+	for _, staged := range []bool{false, true} {
+		if ctx.Err() != nil {
+			break
+		}
+
+		rb := NewbufferWithMetrics(BufferSize)
+		var wg sync.WaitGroup
+		var producersDone atomic.Bool
+		start := time.Now()
+
+		msgsPerProducer := ShardEvents / NumProducers
+		wg.Add(NumProducers)
+		for p := 0; p < NumProducers; p++ {
+			go func() {
+				defer wg.Done()
+				enqueue := rb.Enqueue
+				var handle *Batcher
+				if staged {
+					handle = rb.NewBatcher()
+					enqueue = handle.Enqueue
+				}
+				for i := 0; i < msgsPerProducer && ctx.Err() == nil; i++ {
+					for !enqueue(uint64(i), 100.0, 1) {
+						runtime.Gosched()
+					}
+				}
+				if handle != nil {
+					handle.Close()
+				}
+			}()
+		}
+
+		var consumerWg sync.WaitGroup
+		consumerWg.Add(NumConsumers)
+		for c := 0; c < NumConsumers; c++ {
+			go func() {
+				defer consumerWg.Done()
+				ids, prices, qtys := makeBatch()
+				for {
+					finished := producersDone.Load()
+					if rb.DequeueBatchUpTo(ids, prices, qtys) == 0 {
+						if finished {
+							return
+						}
+						runtime.Gosched()
+					}
+				}
+			}()
+		}
+
+		wg.Wait()
+		producersDone.Store(true)
+		consumerWg.Wait()
+
+		st := rb.Stats()
+		name := "RingBuffer.Enqueue"
+		if staged {
+			name = "Batcher.Enqueue"
+		}
+		fmt.Printf(">> %-18s %12.0f ops/sec, %.3f CAS retries/item\n", name,
+			float64(st.Dequeued)/time.Since(start).Seconds(), float64(st.EnqueueRetries)/float64(max(st.Enqueued, 1)))
+	}
+	fmt.Println("---------------------------------------------------------")
+}
+
+// runAdaptiveBatchBenchmark compares Batchers with a fixed batch of
+// BatchSize against WithAdaptiveBatch(1, 256) while the consumers alternate
+// between full speed and pausing AdaptiveSlowDelay after every batch, every
+// AdaptivePhase. It reports throughput, how often a producer found its
+// staging area full and had to yield, and how often a flush found no room.
+func runAdaptiveBatchBenchmark(ctx context.Context) {
+	fmt.Println("Running Adaptive Batch Benchmark...")
+
+	modes := []struct {
+		name string
+		opt  BatcherOption
+	}{
+		{fmt.Sprintf("fixed %d", BatchSize), WithBatchSize(BatchSize)},
+		{"adaptive 1-256", WithAdaptiveBatch(1, 256)},
+	}
+	for _, m := range modes {
+		if ctx.Err() != nil {
+			break
+		}
+
+		rb := NewbufferWithMetrics(BufferSize)
+		var wg sync.WaitGroup
+		var producersDone atomic.Bool
+		var yields atomic.Uint64
+		start := time.Now()
+
+		msgsPerProducer := AdaptiveEvents / NumProducers
+		wg.Add(NumProducers)
+		for p := 0; p < NumProducers; p++ {
+			go func() {
+				defer wg.Done()
+				handle := rb.NewBatcher(m.opt)
+				for i := 0; i < msgsPerProducer && ctx.Err() == nil; i++ {
+					for !handle.Enqueue(uint64(i), 100.0, 1) {
+						yields.Add(1)
+						runtime.Gosched()
+					}
+				}
+				for handle.Staged() > 0 {
+					if handle.Flush() == 0 {
+						yields.Add(1)
+						runtime.Gosched()
+					}
+				}
+			}()
+		}
+
+		var consumerWg sync.WaitGroup
+		consumerWg.Add(NumConsumers)
+		for c := 0; c < NumConsumers; c++ {
+			go func() {
+				defer consumerWg.Done()
+				ids, prices, qtys := makeBatch()
+				for {
+					finished := producersDone.Load()
+					if rb.DequeueBatchUpTo(ids, prices, qtys) == 0 {
+						if finished {
+							return
+						}
+						runtime.Gosched()
+						continue
+					}
+					if time.Since(start)/AdaptivePhase%2 == 1 {
+						time.Sleep(AdaptiveSlowDelay)
+					}
+				}
+			}()
+		}
+
+		wg.Wait()
+		producersDone.Store(true)
+		consumerWg.Wait()
+
+		st := rb.Stats()
+		fmt.Printf(">> %-15s %12.0f ops/sec, %.3f full yields/item, %.3f full flushes/item\n", m.name,
+			float64(st.Dequeued)/time.Since(start).Seconds(),
+			float64(yields.Load())/float64(max(st.Enqueued, 1)),
+			float64(st.FullAttempts)/float64(max(st.Enqueued, 1)))
+	}
+	fmt.Println("---------------------------------------------------------")
+}
+
+// runFanBenchmark runs the fan-in and fan-out topologies through RingBuffer
+// and through the matching specialised buffer.
+func runFanBenchmark(ctx context.Context) {
+	fmt.Printf("Running Fan-In (%dP/1C) and Fan-Out (1P/%dC) Benchmark...\n", NumProducers, NumConsumers)
+
+	mpmc := runSingleItemBenchmark(ctx, Newbuffer(BufferSize), NumProducers, 1)
+	mpsc := runSingleItemBenchmark(ctx, NewMPSCBuffer(BufferSize), NumProducers, 1)
+	fmt.Printf(">> Fan-in:  RingBuffer %12.0f ops/sec, MPSCBuffer %12.0f ops/sec\n", mpmc, mpsc)
+	if ctx.Err() != nil {
+		return
+	}
+
+	mpmc = runSingleItemBenchmark(ctx, Newbuffer(BufferSize), 1, NumConsumers)
+	spmc := runSingleItemBenchmark(ctx, NewSPMCBuffer(BufferSize), 1, NumConsumers)
+	fmt.Printf(">> Fan-out: RingBuffer %12.0f ops/sec, SPMCBuffer %12.0f ops/sec\n", mpmc, spmc)
+	fmt.Println("---------------------------------------------------------")
+}
+
+// runSingleItemBenchmark moves ShardEvents items through q one at a time and
+// returns the throughput. A RingBuffer is driven through EnqueueContext and
+// DequeueContext, which wait on the buffer themselves and give up once ctx is
+// cancelled and the buffer is empty, and is closed when the producers are done
+// so that DequeueContext reports the end of the stream. Other queues retry by
+// hand and check ctx between attempts.
+func runSingleItemBenchmark(ctx context.Context, q singleItemQueue, producers, consumers int) float64 {
+	var producersDone atomic.Bool
+	produce := func(id uint64) bool {
+		for !q.Enqueue(id, 100.0, 1) {
+			if ctx.Err() != nil {
+				return false
+			}
+			runtime.Gosched()
+		}
+		return true
+	}
+	consume := func() bool {
+		var id uint64
+		var price float64
+		var qty uint32
+		for {
+			// Once producers are done, a failed Dequeue means empty.
+			finished := producersDone.Load()
+			if q.Dequeue(&id, &price, &qty) {
+				return true
+			}
+			if finished {
+				return false
+			}
+			runtime.Gosched()
+		}
+	}
+	finish := func() { producersDone.Store(true) }
+	if rb, ok := q.(*RingBuffer); ok {
+		produce = func(id uint64) bool { return rb.EnqueueContext(ctx, id, 100.0, 1) == nil }
+		consume = func() bool {
+			_, err := rb.DequeueContext(ctx)
+			return err == nil
+		}
+		finish = rb.Close
+	}
+
+	var wg sync.WaitGroup
+	var consumed atomic.Uint64
+
+	start := time.Now()
+
+	msgsPerProducer := ShardEvents / producers
+	wg.Add(producers)
+	for p := 0; p < producers; p++ {
+		go func() {
+			defer wg.Done()
+			for i := 0; i < msgsPerProducer && ctx.Err() == nil && produce(uint64(i)); i++ {
+			}
+		}()
+	}
+
+	var consumerWg sync.WaitGroup
+	consumerWg.Add(consumers)
+	for c := 0; c < consumers; c++ {
+		go func() {
+			defer consumerWg.Done()
+			processed := 0
+			for consume() {
+				processed++
+			}
+			consumed.Add(uint64(processed))
+		}()
+	}
+
+	wg.Wait()
+	finish()
+	consumerWg.Wait()
+
+	return float64(consumed.Load()) / time.Since(start).Seconds()
+}
+
+func reportProgress(ctx context.Context, duration time.Duration, events uint64) {
+	if ctx.Err() != nil {
+		fmt.Printf("Interrupted after %v (%d of %d events)\n", duration, events, TotalEvents)
+		return
+	}
 	fmt.Printf("Done in %v\n", duration)
-	fmt.Printf(">> RingBuffer Throughput: %.0f ops/sec\n", ops)
+}
+
+// runGenericBenchmark runs the batch workload through RingBufferG[Order], so
+// the summary shows what the generic slot layout costs against the columns.
+func runGenericBenchmark(ctx context.Context) float64 {
+	fmt.Print("Running RingBufferG[Order] Batch Benchmark...  ")
+
+	rb := NewbufferG[Order](BufferSize)
+	var wg sync.WaitGroup
+
+	start := time.Now()
+
+	msgsPerProducer := TotalEvents / NumProducers
+	wg.Add(NumProducers)
+	for p := 0; p < NumProducers; p++ {
+		go func() {
+			defer wg.Done()
+			batch := make([]Order, BatchSize)
+			for k := range batch {
+				batch[k] = Order{ID: uint64(k), Price: 100.0, Qty: 1}
+			}
+
+			loops := msgsPerProducer / BatchSize
+			for i := 0; i < loops && ctx.Err() == nil; i++ {
+				for rb.EnqueueBatch(batch) == 0 {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+
+	var producersDone atomic.Bool
+	var consumed atomic.Uint64
+
+	msgsPerConsumer := TotalEvents / NumConsumers
+	var consumerWg sync.WaitGroup
+	consumerWg.Add(NumConsumers)
+	for c := 0; c < NumConsumers; c++ {
+		go func() {
+			defer consumerWg.Done()
+			batch := make([]Order, BatchSize)
+
+			processed := 0
+			for processed < msgsPerConsumer {
+				n := rb.DequeueBatch(batch)
+				if n > 0 {
+					processed += int(n)
+				} else if producersDone.Load() {
+					break
+				} else {
+					runtime.Gosched()
+				}
+			}
+			consumed.Add(uint64(processed))
+		}()
+	}
+
+	wg.Wait()
+	if ctx.Err() != nil {
+		producersDone.Store(true)
+	}
+	consumerWg.Wait()
+
+	duration := time.Since(start)
+	ops := float64(consumed.Load()) / duration.Seconds()
+	reportProgress(ctx, duration, consumed.Load())
+	fmt.Printf(">> RingBufferG[Order] Throughput: %.0f ops/sec\n", ops)
 	fmt.Println("---------------------------------------------------------")
-}
\ No newline at end of file
+	return ops
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// withEvents sets TotalEvents for the duration of the test.
+func withEvents(t *testing.T, n int) {
+	old := TotalEvents
+	TotalEvents = n
+	t.Cleanup(func() { TotalEvents = old })
+}
+
+// TestRunAllCancel starts the benchmark with a workload far too large to
+// finish and cancels it mid-run, as SIGINT does. The run in progress must
+// stop and report, and the remaining runs must be skipped.
+func TestRunAllCancel(t *testing.T) {
+	withEvents(t, 1<<40)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	done := make(chan []result, 1)
+	go func() { done <- runAll(ctx) }()
+	select {
+	case results := <-done:
+		if len(results) != 1 || results[0].name != "Go Channel" {
+			t.Fatalf("runAll returned %v, want only the interrupted channel run", results)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("runAll did not return after the cancel")
+	}
+}
+
+func TestRunBatchBenchmarkCancel(t *testing.T) {
+	withEvents(t, 1<<40)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	rb := Newbuffer(BufferSize)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runBatchBenchmark(ctx, "RingBuffer", rb)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("runBatchBenchmark did not return after the cancel")
+	}
+	if !rb.IsEmpty() {
+		t.Errorf("%d items left in the buffer, want the consumers to drain it", rb.Len())
+	}
+}
+
+// TestRunSingleItemBenchmarkCancelled checks both worker paths of
+// runSingleItemBenchmark, EnqueueContext on a RingBuffer and the retry loop
+// for other queues, against a context that is already cancelled: nothing is
+// produced and the consumers still return.
+func TestRunSingleItemBenchmarkCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	queues := []struct {
+		name string
+		q    singleItemQueue
+	}{
+		{"RingBuffer", Newbuffer(16)},
+		{"ShardedBuffer", NewShardedBuffer(2, 8)},
+	}
+	for _, q := range queues {
+		t.Run(q.name, func(t *testing.T) {
+			if ops := runSingleItemBenchmark(ctx, q.q, 2, 2); ops != 0 {
+				t.Fatalf("throughput %.0f ops/sec after the cancel, want 0", ops)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"sync/atomic"
+)
+
+const (
+	encodingVersion = 1
+	encodingHeader  = 1 + 8 + 8 // version, capacity, count
+	encodedOrder    = 8 + 8 + 4 // id, price bits, qty
+
+	// maxDecodedCapacity is the largest capacity UnmarshalBinary allocates
+	// on the encoding's word alone, without items to back it.
+	maxDecodedCapacity = 1 << 20
+)
+
+// MarshalBinary encodes the buffer's capacity and its pending items in FIFO
+// order, for a warm start after a restart. The indices, the closed flag and
+// the counters are not part of it. The format is a version byte, then the
+// capacity and the item count as little-endian uint64s, then each item as
+// its ID, its price's IEEE 754 bits and its quantity, little-endian. Like
+// Resize it must only be called on a quiescent buffer; a claimed but
+// unpublished slot makes it fail with ErrMarshalBusy.
+func (rb *RingBuffer) MarshalBinary() ([]byte, error) {
+	tail := atomic.LoadUint64(&rb.readIndex)
+	head := atomic.LoadUint64(&rb.writeIndex)
+	pending := head - tail
+	if n, _ := rb.readable(tail, pending); n != pending {
+		return nil, rb.newError("MarshalBinary", pending, ErrMarshalBusy)
+	}
+
+	buf := make([]byte, 0, encodingHeader+pending*encodedOrder)
+	buf = append(buf, encodingVersion)
+	buf = binary.LittleEndian.AppendUint64(buf, rb.capacity)
+	buf = binary.LittleEndian.AppendUint64(buf, pending)
+	for seq := tail; seq != head; seq++ {
+		offset := seq & rb.mask
+		buf = binary.LittleEndian.AppendUint64(buf, rb.ids[offset])
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(rb.prices[offset]))
+		buf = binary.LittleEndian.AppendUint32(buf, rb.qtys[offset])
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary replaces the buffer's contents with the items encoded by
+// MarshalBinary, in the same order, as if freshly enqueued into a buffer of
+// the encoded capacity. A buffer built with Newbuffer or NewbufferOpts keeps
+// its options and is reset, and resized if the capacity differs; a zero
+// RingBuffer becomes one with the default options. Like Reset it must only
+// be called on a quiescent buffer. Malformed input fails with
+// ErrInvalidEncoding and leaves the buffer unchanged.
+//
+// The capacity is taken from the input, so a few bytes could otherwise ask
+// for gigabytes. UnmarshalBinary accepts a capacity up to the buffer's own
+// or 1<<20 slots, whichever is larger, and beyond that only the capacity the
+// encoded items need; use UnmarshalBinaryLimit to allow more.
+func (rb *RingBuffer) UnmarshalBinary(data []byte) error {
+	return rb.UnmarshalBinaryLimit(data, max(maxDecodedCapacity, rb.capacity))
+}
+
+// UnmarshalBinaryLimit is UnmarshalBinary accepting encoded capacities up to
+// maxCapacity, or up to the encoded item count rounded up to a power of two
+// if that is larger.
+func (rb *RingBuffer) UnmarshalBinaryLimit(data []byte, maxCapacity uint64) error {
+	if len(data) < encodingHeader || data[0] != encodingVersion {
+		return rb.newError("UnmarshalBinary", uint64(len(data)), ErrInvalidEncoding)
+	}
+	capacity := binary.LittleEndian.Uint64(data[1:])
+	count := binary.LittleEndian.Uint64(data[9:])
+	items := data[encodingHeader:]
+	if count > uint64(len(items))/encodedOrder || uint64(len(items)) != count*encodedOrder {
+		return rb.newError("UnmarshalBinary", uint64(len(data)), ErrInvalidEncoding)
+	}
+	if !validCapacity(capacity) || count > capacity || capacity > max(maxCapacity, ceilPow2(count)) {
+		return rb.newError("UnmarshalBinary", uint64(len(data)), ErrInvalidEncoding)
+	}
+
+	if rb.cycleState == nil {
+		*rb = *NewbufferOpts(capacity)
+	} else {
+		rb.Reset()
+		if capacity != rb.capacity {
+			if err := rb.Resize(capacity); err != nil {
+				return err
+			}
+		}
+	}
+
+	for seq := uint64(0); seq < count; seq++ {
+		item := items[seq*encodedOrder:]
+		rb.ids[seq] = binary.LittleEndian.Uint64(item)
+		rb.prices[seq] = math.Float64frombits(binary.LittleEndian.Uint64(item[8:]))
+		rb.qtys[seq] = binary.LittleEndian.Uint32(item[16:])
+		storeCycle(&rb.cycleState[seq], seq+1)
+	}
+	atomic.StoreUint64(&rb.writeIndex, count)
+	return nil
+}
+
+// ceilPow2 returns the smallest power of two that is at least n.
+func ceilPow2(n uint64) uint64 {
+	if n <= 1 {
+		return 1
+	}
+	return 1 << bits.Len64(n-1)
+}
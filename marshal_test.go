@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestMarshalRoundTrip(t *testing.T) {
+	src := Newbuffer(16)
+	// Move the indices off zero so the encoding has to wrap.
+	for i := range 10 {
+		src.Enqueue(uint64(i), 0, 0)
+		src.DequeueOrder()
+	}
+	want := []Order{{ID: 1, Price: 1.5, Qty: 10}, {ID: 2, Price: -3.25, Qty: 20}, {ID: 3, Price: 0, Qty: 30}}
+	for _, o := range want {
+		src.EnqueueOrder(o)
+	}
+
+	data, err := src.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	for _, dst := range []*RingBuffer{new(RingBuffer), Newbuffer(4), Newbuffer(16), Newbuffer(64)} {
+		if err := dst.UnmarshalBinary(data); err != nil {
+			t.Fatalf("UnmarshalBinary into capacity %d: %v", dst.Cap(), err)
+		}
+		if dst.Cap() != 16 {
+			t.Errorf("Cap() = %d, want 16", dst.Cap())
+		}
+		for i, w := range want {
+			got, ok := dst.DequeueOrder()
+			if !ok || got != w {
+				t.Fatalf("item %d = %+v, %v, want %+v", i, got, ok, w)
+			}
+		}
+		if !dst.IsEmpty() {
+			t.Errorf("Len() = %d after reading every item", dst.Len())
+		}
+	}
+}
+
+func TestUnmarshalRejectsMalformedInput(t *testing.T) {
+	src := Newbuffer(8)
+	src.Enqueue(1, 1, 1)
+	src.Enqueue(2, 2, 2)
+	valid, err := src.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	header := func(capacity, count uint64) []byte {
+		b := []byte{encodingVersion}
+		b = binary.LittleEndian.AppendUint64(b, capacity)
+		return binary.LittleEndian.AppendUint64(b, count)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"short header", valid[:encodingHeader-1]},
+		{"bad version", append([]byte{encodingVersion + 1}, valid[1:]...)},
+		{"truncated item", valid[:len(valid)-1]},
+		{"trailing bytes", append(append([]byte{}, valid...), 0)},
+		{"count overflows length", header(8, 1<<63)},
+		{"count wraps multiplication", append(header(8, 1<<62+1), make([]byte, encodedOrder)...)},
+		{"count above capacity", append(header(2, 3), make([]byte, 3*encodedOrder)...)},
+		{"capacity not a power of two", header(12, 0)},
+		{"capacity one", header(1, 0)},
+		{"hostile capacity", header(1<<40, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rb := Newbuffer(8)
+			rb.Enqueue(42, 0, 0)
+			err := rb.UnmarshalBinary(tt.data)
+			if !errors.Is(err, ErrInvalidEncoding) {
+				t.Fatalf("UnmarshalBinary = %v, want ErrInvalidEncoding", err)
+			}
+			if rb.Cap() != 8 || rb.Len() != 1 {
+				t.Fatalf("buffer changed: Cap() = %d, Len() = %d", rb.Cap(), rb.Len())
+			}
+		})
+	}
+}
+
+func TestUnmarshalBinaryLimit(t *testing.T) {
+	data := []byte{encodingVersion}
+	data = binary.LittleEndian.AppendUint64(data, 1<<21)
+	data = binary.LittleEndian.AppendUint64(data, 0)
+
+	var rb RingBuffer
+	if err := rb.UnmarshalBinary(data); !errors.Is(err, ErrInvalidEncoding) {
+		t.Fatalf("UnmarshalBinary = %v, want ErrInvalidEncoding", err)
+	}
+	if err := rb.UnmarshalBinaryLimit(data, 1<<21); err != nil {
+		t.Fatalf("UnmarshalBinaryLimit: %v", err)
+	}
+	if rb.Cap() != 1<<21 {
+		t.Fatalf("Cap() = %d, want %d", rb.Cap(), 1<<21)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import "sync/atomic"
+
+// MPSCBuffer is a fan-in ring: any number of producer goroutines, exactly one
+// consumer goroutine. Producers claim slots with a CAS on writeIndex and
+// publish through the per-slot cycle state as in RingBuffer. The consumer owns
+// readIndex outright, so it advances it with a plain store and never CASes.
+// Dequeue from two goroutines at once hands the same item to both and
+// corrupts the buffer; see cmd/racedemo.
+type MPSCBuffer struct {
+	capacity uint64
+	mask     uint64
+	_        [CacheLineSize]byte
+
+	writeIndex uint64
+	_          [CacheLineSize - 8]byte
+
+	readIndex uint64
+	_         [CacheLineSize - 8]byte
+
+	cycleState []cycle
+	ids        []uint64
+	prices     []float64
+	qtys       []uint32
+}
+
+// NewMPSCBuffer returns a buffer of the given capacity, which must be a power
+// of two.
+func NewMPSCBuffer(capacity uint64) *MPSCBuffer {
+	checkCapacity("NewMPSCBuffer", capacity)
+
+	buffer := &MPSCBuffer{
+		capacity:   capacity,
+		mask:       capacity - 1,
+		cycleState: make([]cycle, capacity),
+		ids:        make([]uint64, capacity),
+		prices:     make([]float64, capacity),
+		qtys:       make([]uint32, capacity),
+	}
+	initCycleState(buffer.cycleState)
+	return buffer
+}
+
+func (rb *MPSCBuffer) Enqueue(id uint64, price float64, qty uint32) bool {
+	var head uint64
+	var offset uint64
+
+	for {
+		head = atomic.LoadUint64(&rb.writeIndex)
+		offset = head & rb.mask
+		diff := cycleDiff(loadCycle(&rb.cycleState[offset]), head)
+
+		if diff == 0 {
+			if atomic.CompareAndSwapUint64(&rb.writeIndex, head, head+1) {
+				break
+			}
+		} else if diff < 0 {
+			return false
+		}
+	}
+
+	rb.ids[offset] = id
+	rb.prices[offset] = price
+	rb.qtys[offset] = qty
+	storeCycle(&rb.cycleState[offset], head+1)
+	return true
+}
+
+// Dequeue must only be called from the single consumer goroutine.
+func (rb *MPSCBuffer) Dequeue(id *uint64, price *float64, qty *uint32) bool {
+	tail := rb.readIndex
+	offset := tail & rb.mask
+	if cycleDiff(loadCycle(&rb.cycleState[offset]), tail+1) != 0 {
+		return false
+	}
+
+	*id = rb.ids[offset]
+	*price = rb.prices[offset]
+	*qty = rb.qtys[offset]
+	storeCycle(&rb.cycleState[offset], tail+rb.capacity)
+	rb.readIndex = tail + 1
+	return true
+}
@@ -0,0 +1,133 @@
+//go:build linux && numa
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const handoffRounds = 200_000
+
+func init() {
+	extraBenchmarks = append(extraBenchmarks, runNUMAHandoffBenchmark)
+}
+
+// runNUMAHandoffBenchmark measures single-item handoff latency between two
+// pinned threads, once with both on node 0 and once across nodes 0 and 1.
+// Each round is a ping-pong over two buffers, so the clock is only read on
+// one thread and the one-way latency is half the round trip.
+func runNUMAHandoffBenchmark(context.Context) {
+	fmt.Println("Running NUMA Handoff Benchmark...")
+
+	node0, err := nodeCPUs(0)
+	if err != nil {
+		fmt.Printf(">> Skipped: %v\n", err)
+		fmt.Println("---------------------------------------------------------")
+		return
+	}
+	node1, err := nodeCPUs(1)
+	if err != nil {
+		fmt.Println(">> Skipped: single NUMA node")
+		fmt.Println("---------------------------------------------------------")
+		return
+	}
+
+	if len(node0) > 1 {
+		reportHandoff("Same node ", node0[0], node0[1])
+	}
+	reportHandoff("Cross node", node0[0], node1[0])
+	fmt.Println("---------------------------------------------------------")
+}
+
+func reportHandoff(label string, producerCPU, consumerCPU int) {
+	latency, err := handoff(producerCPU, consumerCPU)
+	if err != nil {
+		fmt.Printf(">> %s (cpu %d -> cpu %d): skipped: %v\n", label, producerCPU, consumerCPU, err)
+		return
+	}
+	fmt.Printf(">> %s (cpu %d -> cpu %d): %v\n", label, producerCPU, consumerCPU, latency)
+}
+
+func handoff(producerCPU, consumerCPU int) (time.Duration, error) {
+	ping := Newbuffer(2)
+	pong := Newbuffer(2)
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	restore, err := pinThread(producerCPU)
+	if err != nil {
+		return 0, err
+	}
+	defer restore()
+
+	pinned := make(chan error)
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		restore, err := pinThread(consumerCPU)
+		pinned <- err
+		if err != nil {
+			return
+		}
+		defer restore()
+
+		var id uint64
+		var price float64
+		var qty uint32
+		for i := 0; i < handoffRounds; i++ {
+			for !ping.Dequeue(&id, &price, &qty) {
+			}
+			for !pong.Enqueue(id, price, qty) {
+			}
+		}
+	}()
+
+	if err := <-pinned; err != nil {
+		return 0, err
+	}
+
+	var id uint64
+	var price float64
+	var qty uint32
+	start := time.Now()
+	for i := 0; i < handoffRounds; i++ {
+		for !ping.Enqueue(uint64(i), 100.0, 1) {
+		}
+		for !pong.Dequeue(&id, &price, &qty) {
+		}
+	}
+	return time.Since(start) / (2 * handoffRounds), nil
+}
+
+// nodeCPUs parses /sys/devices/system/node/nodeN/cpulist, e.g. "0-3,8-11".
+func nodeCPUs(node int) ([]int, error) {
+	raw, err := os.ReadFile(fmt.Sprintf("/sys/devices/system/node/node%d/cpulist", node))
+	if err != nil {
+		return nil, err
+	}
+
+	var cpus []int
+	for _, part := range strings.Split(strings.TrimSpace(string(raw)), ",") {
+		lo, hi, found := strings.Cut(part, "-")
+		first, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if found {
+			if last, err = strconv.Atoi(hi); err != nil {
+				return nil, err
+			}
+		}
+		for c := first; c <= last; c++ {
+			cpus = append(cpus, c)
+		}
+	}
+	return cpus, nil
+}
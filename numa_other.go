@@ -0,0 +1,16 @@
+//go:build numa && !linux
+
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+func init() {
+	extraBenchmarks = append(extraBenchmarks, func(context.Context) {
+		fmt.Println("Running NUMA Handoff Benchmark...")
+		fmt.Println(">> Skipped: CPU pinning is only supported on Linux")
+		fmt.Println("---------------------------------------------------------")
+	})
+}
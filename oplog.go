@@ -0,0 +1,143 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// OpKind says whether an Op put an item into the buffer or took one out.
+type OpKind uint8
+
+const (
+	OpEnqueue OpKind = iota
+	OpDequeue
+)
+
+func (k OpKind) String() string {
+	if k == OpEnqueue {
+		return "enqueue"
+	}
+	return "dequeue"
+}
+
+// Op is one item moved by a recorded operation: the sequence it occupied and
+// the order that was enqueued or dequeued there.
+type Op struct {
+	Kind  OpKind
+	Seq   uint64
+	Order Order
+}
+
+func (op Op) String() string {
+	return fmt.Sprintf("%v seq %d %+v", op.Kind, op.Seq, op.Order)
+}
+
+// operationLog keeps the last len(ops) Ops in a ring of its own. It takes a
+// mutex per item, which is fine for reproducing a bug and far too slow for
+// production.
+type operationLog struct {
+	mu   sync.Mutex
+	ops  []Op
+	next uint64
+}
+
+// WithOperationLog records the last n items moved through the buffer, for
+// OperationLog and Replay. Enqueue, EnqueueBatch, EnqueueBatchPartial,
+// Dequeue and DequeueBatch are recorded, along with the calls built on them
+// such as EnqueueOrder, DequeueInto and the blocking and context variants.
+// The other batch, claim and drain paths are not, nor are items dropped by
+// PolicyOverwrite, and a log that includes them won't replay. Reset clears
+// the log.
+func WithOperationLog(n int) Option {
+	return func(c *bufferConfig) { c.opLogSize = n }
+}
+
+func newOperationLog(n int) *operationLog {
+	if n <= 0 {
+		return nil
+	}
+	return &operationLog{ops: make([]Op, n)}
+}
+
+func (l *operationLog) record(kind OpKind, seq uint64, id uint64, price float64, qty uint32) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	l.ops[l.next%uint64(len(l.ops))] = Op{Kind: kind, Seq: seq, Order: Order{ID: id, Price: price, Qty: qty}}
+	l.next++
+	l.mu.Unlock()
+}
+
+func (l *operationLog) clear() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	l.next = 0
+	l.mu.Unlock()
+}
+
+// OperationLog returns the recorded Ops, oldest first, or nil if the buffer
+// was built without WithOperationLog. Ops are logged after their index
+// claim, so concurrent producers or consumers may appear out of sequence
+// order; Replay sorts that out.
+func (rb *RingBuffer) OperationLog() []Op {
+	l := rb.oplog
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	size := uint64(len(l.ops))
+	if l.next <= size {
+		return slices.Clone(l.ops[:l.next])
+	}
+	start := l.next % size
+	return append(slices.Clone(l.ops[start:]), l.ops[:start]...)
+}
+
+// Replay applies log to target from a single goroutine, so a run recorded
+// under concurrency can be stepped through deterministically. Enqueues and
+// dequeues are each put in sequence order and merged so that every dequeue
+// comes after the enqueue of its sequence and before the enqueue that reuses
+// its slot, which is the order the original run must have had. Replay stops
+// with an error when target diverges: a full buffer, an empty one, or a
+// dequeued order different from the recorded one.
+//
+// A log that has wrapped starts mid-stream. Replaying it into a fresh buffer
+// only reproduces the original if the buffer was empty where the log begins;
+// otherwise the first dequeues find nothing or the wrong items.
+func Replay(log []Op, target *RingBuffer) error {
+	var enqueues, dequeues []Op
+	for _, op := range log {
+		if op.Kind == OpEnqueue {
+			enqueues = append(enqueues, op)
+		} else {
+			dequeues = append(dequeues, op)
+		}
+	}
+	bySeq := func(a, b Op) int { return cmp.Compare(a.Seq, b.Seq) }
+	slices.SortStableFunc(enqueues, bySeq)
+	slices.SortStableFunc(dequeues, bySeq)
+
+	for len(enqueues) > 0 || len(dequeues) > 0 {
+		if len(dequeues) > 0 && (len(enqueues) == 0 || dequeues[0].Seq < enqueues[0].Seq) {
+			op := dequeues[0]
+			dequeues = dequeues[1:]
+			got, ok := target.DequeueOrder()
+			if !ok || got != op.Order {
+				return fmt.Errorf("replaying %v: dequeued %+v, %v", op, got, ok)
+			}
+			continue
+		}
+		op := enqueues[0]
+		enqueues = enqueues[1:]
+		if !target.EnqueueOrder(op.Order) {
+			return fmt.Errorf("replaying %v: buffer full", op)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// TestReplay records a concurrent run of single and batch producers and
+// consumers that stops with items still buffered, replays the log into a
+// fresh buffer and expects the same contents.
+func TestReplay(t *testing.T) {
+	const (
+		workers   = 4
+		perWorker = 2_000
+		batch     = 4
+	)
+	rb := NewbufferOpts(64, WithOperationLog(2*workers*perWorker))
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for p := range workers {
+		go func() {
+			defer wg.Done()
+			ids := make([]uint64, batch)
+			prices := make([]float64, batch)
+			qtys := make([]uint32, batch)
+			for next := uint64(p * perWorker); next < uint64((p+1)*perWorker); {
+				if next%8 == 0 {
+					for i := range ids {
+						ids[i], prices[i], qtys[i] = next+uint64(i), float64(next), uint32(p)
+					}
+					for rb.EnqueueBatch(ids, prices, qtys) == 0 {
+						runtime.Gosched()
+					}
+					next += batch
+					continue
+				}
+				for !rb.Enqueue(next, float64(next), uint32(p)) {
+					runtime.Gosched()
+				}
+				next++
+			}
+		}()
+	}
+
+	// Consume all but the last 20 items, alternating single and batch
+	// dequeues.
+	ids := make([]uint64, batch)
+	prices := make([]float64, batch)
+	qtys := make([]uint32, batch)
+	var o Order
+	for consumed := 0; consumed < workers*perWorker-20; {
+		if consumed%2 == 0 && consumed+batch <= workers*perWorker-20 && rb.DequeueBatch(ids, prices, qtys) > 0 {
+			consumed += batch
+		} else if rb.DequeueInto(&o) {
+			consumed++
+		} else {
+			runtime.Gosched()
+		}
+	}
+	wg.Wait()
+
+	log := rb.OperationLog()
+	if len(log) != 2*workers*perWorker-20 {
+		t.Fatalf("logged %d ops, want %d", len(log), 2*workers*perWorker-20)
+	}
+	replayed := Newbuffer(64)
+	if err := Replay(log, replayed); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := rb.MarshalBinary()
+	got, _ := replayed.MarshalBinary()
+	if !bytes.Equal(got, want) || replayed.Len() != 20 {
+		t.Fatalf("replayed buffer holds %d items and differs from the original's %d", replayed.Len(), rb.Len())
+	}
+}
+
+func TestOperationLogWraps(t *testing.T) {
+	rb := NewbufferOpts(8, WithOperationLog(3))
+	for i := range uint64(4) {
+		rb.Enqueue(i, 0, 0)
+	}
+	rb.DequeueOrder()
+
+	log := rb.OperationLog()
+	want := []Op{{OpEnqueue, 2, Order{ID: 2}}, {OpEnqueue, 3, Order{ID: 3}}, {OpDequeue, 0, Order{ID: 0}}}
+	if len(log) != len(want) {
+		t.Fatalf("OperationLog() = %v, want %v", log, want)
+	}
+	for i := range want {
+		if log[i] != want[i] {
+			t.Fatalf("OperationLog() = %v, want %v", log, want)
+		}
+	}
+
+	// The window starts with items 0 and 1 already buffered, so replaying
+	// it into an empty buffer diverges.
+	if err := Replay(log, Newbuffer(8)); err == nil {
+		t.Fatal("Replay of a mid-stream log into an empty buffer succeeded")
+	}
+
+	rb.Reset()
+	if log := rb.OperationLog(); len(log) != 0 {
+		t.Fatalf("OperationLog() after Reset = %v, want empty", log)
+	}
+	if log := Newbuffer(8).OperationLog(); log != nil {
+		t.Fatalf("OperationLog() without the option = %v, want nil", log)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import "fmt"
+
+// Option configures a buffer built with NewbufferOpts.
+type Option func(*bufferConfig)
+
+type bufferConfig struct {
+	wait       WaitStrategy
+	spinWait   WaitStrategy
+	policy     Policy
+	metrics    bool
+	retryLimit int
+	batchSize  int
+	contention *contentionWarning
+	selfTest   *selfTest
+	batchDedup bool
+	opLogSize  int
+	backing    BackingProvider
+	spillLimit int
+}
+
+type selfTest struct {
+	floor  float64
+	report func(SelfTestReport)
+}
+
+// WithWaitStrategy sets what the blocking operations do between retries. The
+// default is Yield.
+func WithWaitStrategy(ws WaitStrategy) Option {
+	return func(c *bufferConfig) { c.wait = ws }
+}
+
+// WithSpinWait sets how consumers wait for a slot that a producer has claimed
+// but not yet published, inside DequeueBatch and DequeueBatchFair. The default
+// is DefaultSpinWait.
+func WithSpinWait(ws WaitStrategy) Option {
+	return func(c *bufferConfig) { c.spinWait = ws }
+}
+
+// WithRetryLimit makes DequeueBatch return 0 after losing n CAS races on
+// readIndex within one call, instead of retrying until it wins. A consumer
+// that keeps losing then backs off in its own loop, and no single call
+// runs for long. The default, 0, retries without limit.
+func WithRetryLimit(n int) Option {
+	return func(c *bufferConfig) { c.retryLimit = n }
+}
+
+// WithBatchBuffers sets the length of the scratch slices GetBatchBuffers
+// returns. The default is 64; either way it is capped at the capacity.
+func WithBatchBuffers(n int) Option {
+	return func(c *bufferConfig) { c.batchSize = n }
+}
+
+// WithBatchDedup makes EnqueueBatch collapse each run of consecutive items
+// with the same ID into its first item, so a batch of IDs 1, 1, 2, 1 stores
+// 1, 2, 1. It only looks within one call: an item is never compared with the
+// previous batch or with what is already in the buffer; DedupBuffer does
+// that. EnqueueBatch then returns how many items were stored, which is fewer
+// than len(ids) when duplicates were dropped.
+func WithBatchDedup() Option {
+	return func(c *bufferConfig) { c.batchDedup = true }
+}
+
+// WithCacheLineSize states the cache line size of the machine the buffer runs
+// on, n bytes, and panics unless the compiled layout keeps the indices at
+// least that far apart. The padding is part of the RingBuffer struct, so it
+// is fixed at build time by CacheLineSize and the nopad tag; this option
+// cannot widen it, only catch a binary built for a smaller line, such as an
+// amd64 layout on a machine with 128-byte lines, or a nopad build, before it
+// silently shares a line between producers and consumers. Any n up to
+// CacheLineSize is already covered and changes nothing.
+func WithCacheLineSize(n int) Option {
+	return func(*bufferConfig) {
+		if n < 1 || n&(n-1) != 0 {
+			panic(fmt.Sprintf("ring: cache line size %d is not a power of two", n))
+		}
+		if n > paddedLine {
+			panic(fmt.Sprintf("ring: cache line size %d exceeds the %s layout's %d-byte padding; rebuild with a wider CacheLineSize", n, Padding, paddedLine))
+		}
+	}
+}
+
+// WithPolicy sets what Enqueue does on a full buffer. The default is
+// PolicyReject.
+func WithPolicy(p Policy) Option {
+	return func(c *bufferConfig) { c.policy = p }
+}
+
+// WithMetrics turns the Stats counters on or off. They are off by default.
+func WithMetrics(enabled bool) Option {
+	return func(c *bufferConfig) { c.metrics = enabled }
+}
+
+// WithContentionWarning calls warn when producers lose the writeIndex CAS
+// more than ratio times per item enqueued, counted since the buffer was built
+// or Reset. Sustained ratios like that usually mean more producer goroutines
+// than cores; fewer producers, Batchers or a ShardedBuffer help. The
+// check reads the Stats counters, so it needs WithMetrics(true) and does
+// nothing without it. It runs every contentionCheck lost races, on the
+// producer that lost the race, and calls warn again at each check while the
+// ratio stays above the threshold, so warn must be quick and safe for
+// concurrent use.
+func WithContentionWarning(ratio float64, warn func(Stats)) Option {
+	return func(c *bufferConfig) { c.contention = &contentionWarning{ratio: ratio, warn: warn} }
+}
+
+// WithStartupSelfTest makes the constructor push a few tens of thousands of
+// items from one goroutine to another through a scratch buffer with the same
+// wait strategies, and hand the measured rate to report before it returns.
+// BelowFloor is set if the rate was under floor items per second, which
+// usually points at the environment rather than the buffer: GOMAXPROCS 1 in a
+// container, a CPU quota, or a sleeping WaitStrategy. The test takes a few
+// milliseconds on a healthy machine and blocks the constructor until done.
+func WithStartupSelfTest(floor float64, report func(SelfTestReport)) Option {
+	return func(c *bufferConfig) { c.selfTest = &selfTest{floor: floor, report: report} }
+}
@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestWithCacheLineSize(t *testing.T) {
+	tests := []struct {
+		n    int
+		want bool
+	}{
+		{0, false},
+		{48, false},
+		{8, paddedLine >= 8},
+		{CacheLineSize, paddedLine == CacheLineSize},
+		{2 * CacheLineSize, false},
+	}
+	for _, tt := range tests {
+		ok := func() (ok bool) {
+			defer func() { ok = recover() == nil }()
+			NewbufferOpts(8, WithCacheLineSize(tt.n))
+			return
+		}()
+		if ok != tt.want {
+			t.Errorf("WithCacheLineSize(%d) on the %s layout: accepted %v, want %v", tt.n, Padding, ok, tt.want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// payloadPrice and payloadQty derive the other two columns from the ID, so a
+// consumer can tell from any item alone whether it read all three fields as
+// the producer wrote them. Both change in every bit position as the ID moves,
+// so a stale or torn read from a slot's previous lap cannot match by chance.
+func payloadPrice(id uint64) float64 {
+	return float64(id * 0x9e3779b97f4a7c15 >> 11)
+}
+
+func payloadQty(id uint64) uint32 {
+	return uint32(id*0xc2b2ae35>>7) ^ uint32(id)
+}
+
+// TestPayloadOrdering checks that the payload columns, written with plain
+// stores, are published by the producer's atomic cycle-state store and seen
+// complete by a consumer whose atomic load observes it. On arm64 the CPU may
+// reorder plain stores, so a missing release or acquire would show up as a
+// consumer reading the previous lap's price or quantity; each consumer
+// checks every item against its ID. Under -race the detector also reports
+// any payload access the atomics do not order, on every architecture:
+//
+//	go test -race -run PayloadOrdering
+//	GOARCH=arm64 go test -race -run PayloadOrdering   # on an arm64 host
+//
+// The buffer is small so slots are reused every few items, which is where a
+// stale read would come from. Producers and consumers alternate single and
+// batch calls, so both publication paths are covered.
+func TestPayloadOrdering(t *testing.T) {
+	const producers, consumers, batch = 4, 4, 8
+	perProducer := 50_000
+	if runtime.GOARCH == "arm64" {
+		perProducer *= 4
+	}
+	if testing.Short() {
+		perProducer /= 10
+	}
+	rb := Newbuffer(16)
+	total := uint64(producers * perProducer)
+
+	var pwg sync.WaitGroup
+	pwg.Add(producers)
+	for p := range producers {
+		go func() {
+			defer pwg.Done()
+			ids := make([]uint64, batch)
+			prices := make([]float64, batch)
+			qtys := make([]uint32, batch)
+			next := uint64(p * perProducer)
+			end := next + uint64(perProducer)
+			for next < end {
+				if next%(2*batch) == 0 && end-next >= batch {
+					for i := range ids {
+						id := next + uint64(i)
+						ids[i], prices[i], qtys[i] = id, payloadPrice(id), payloadQty(id)
+					}
+					for rb.EnqueueBatch(ids, prices, qtys) == 0 {
+						runtime.Gosched()
+					}
+					next += batch
+					continue
+				}
+				for !rb.Enqueue(next, payloadPrice(next), payloadQty(next)) {
+					runtime.Gosched()
+				}
+				next++
+			}
+		}()
+	}
+
+	var consumed, bad atomic.Uint64
+	var firstBad atomic.Value
+	check := func(o Order) {
+		if o.Price != payloadPrice(o.ID) || o.Qty != payloadQty(o.ID) {
+			if bad.Add(1) == 1 {
+				firstBad.Store(o)
+			}
+		}
+	}
+	var cwg sync.WaitGroup
+	cwg.Add(consumers)
+	for c := range consumers {
+		go func() {
+			defer cwg.Done()
+			ids := make([]uint64, batch)
+			prices := make([]float64, batch)
+			qtys := make([]uint32, batch)
+			var o Order
+			for consumed.Load() < total {
+				if c%2 == 0 {
+					n := rb.DequeueBatchUpTo(ids, prices, qtys)
+					for i := range n {
+						check(Order{ID: ids[i], Price: prices[i], Qty: qtys[i]})
+					}
+					if n > 0 {
+						consumed.Add(n)
+						continue
+					}
+				} else if rb.DequeueInto(&o) {
+					check(o)
+					consumed.Add(1)
+					continue
+				}
+				runtime.Gosched()
+			}
+		}()
+	}
+
+	pwg.Wait()
+	cwg.Wait()
+	if n := bad.Load(); n > 0 {
+		o := firstBad.Load().(Order)
+		t.Fatalf("%d of %d items had a payload that does not match their ID; first: %+v, want price %v, qty %d",
+			n, total, o, payloadPrice(o.ID), payloadQty(o.ID))
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import "sync/atomic"
+
+// EnqueueOrders is EnqueueBatch for a slice of orders: it enqueues all of them
+// or none and returns how many were written. Storage stays columnar; the
+// fields are split out as each slot is written.
+func (rb *RingBuffer) EnqueueOrders(orders []Order) uint64 {
+	count := uint64(len(orders))
+	if count == 0 || rb.Closed() {
+		return 0
+	}
+	if count > rb.capacity {
+		panic(rb.newError("EnqueueOrders", count, ErrBatchTooLarge))
+	}
+
+	for {
+		head := atomic.LoadUint64(&rb.writeIndex)
+		ok, stale := rb.canClaim(head, count)
+		if stale {
+			rb.stats.addEnqueueRetry()
+			continue
+		}
+		if !ok {
+			rb.stats.addFull()
+			return 0
+		}
+
+		if atomic.CompareAndSwapUint64(&rb.writeIndex, head, head+count) {
+			for i, o := range orders {
+				seq := head + uint64(i)
+				offset := seq & rb.mask
+
+				rb.ids[offset] = o.ID
+				rb.prices[offset] = o.Price
+				rb.qtys[offset] = o.Qty
+				storeCycle(&rb.cycleState[offset], seq+1)
+			}
+			rb.countEnqueued(count)
+			return count
+		}
+		rb.stats.addWriteCASFailure()
+	}
+}
+
+// DequeueOrders fills dst completely or not at all and returns how many
+// orders were read. Like DequeueBatchNoWait it only claims a range that is
+// already fully published, so it never waits on a slow producer.
+func (rb *RingBuffer) DequeueOrders(dst []Order) uint64 {
+	limit := uint64(len(dst))
+	if limit == 0 {
+		return 0
+	}
+	if limit > rb.capacity {
+		panic(rb.newError("DequeueOrders", limit, ErrBatchTooLarge))
+	}
+
+	for {
+		tail := atomic.LoadUint64(&rb.readIndex)
+		count, stale := rb.readable(tail, limit)
+		if stale {
+			rb.stats.addDequeueRetry()
+			continue
+		}
+		if count < limit {
+			rb.stats.addEmpty()
+			return 0
+		}
+
+		if atomic.CompareAndSwapUint64(&rb.readIndex, tail, tail+limit) {
+			rb.copyOutOrders(tail, dst)
+			rb.stats.addDequeued(limit)
+			return limit
+		}
+		rb.stats.addReadCASFailure()
+	}
+}
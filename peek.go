@@ -0,0 +1,72 @@
+package main
+
+import "sync/atomic"
+
+// Peek returns the item at readIndex without consuming it, or false if that
+// slot is not yet published. The slot's stamp is checked again after the copy
+// and the read is retried if a consumer took the item meanwhile, so the result
+// is never a torn mix of two items. With other consumers running it is only
+// advisory: the item can be gone by the time Peek returns, and a following
+// Dequeue may return a different one. Intended for a single consumer deciding
+// whether to take the next item.
+//
+// The payload columns are read with plain loads, and once a consumer takes
+// the item a producer may rewrite the slot while Peek is copying it. The
+// stamp check discards such a read, so the result is sound, but the race
+// detector still reports the overlap: under -race, call Peek, PeekN, ForEach
+// and Range only while no producer is running.
+func (rb *RingBuffer) Peek() (Order, bool) {
+	for {
+		tail := atomic.LoadUint64(&rb.readIndex)
+		offset := tail & rb.mask
+		if cycleDiff(loadCycle(&rb.cycleState[offset]), tail+1) < 0 {
+			return Order{}, false
+		}
+
+		o := Order{ID: rb.ids[offset], Price: rb.prices[offset], Qty: rb.qtys[offset]}
+		if cycleDiff(loadCycle(&rb.cycleState[offset]), tail+1) == 0 {
+			return o, true
+		}
+	}
+}
+
+// PeekN returns up to n items from readIndex on without consuming them,
+// stopping at the first slot that is not yet published. It has the same
+// caveats as Peek.
+func (rb *RingBuffer) PeekN(n int) []Order {
+	limit := min(uint64(max(n, 0)), rb.capacity)
+
+retry:
+	for {
+		tail := atomic.LoadUint64(&rb.readIndex)
+		out := make([]Order, 0, limit)
+		for i := uint64(0); i < limit; i++ {
+			seq := tail + i
+			offset := seq & rb.mask
+			diff := cycleDiff(loadCycle(&rb.cycleState[offset]), seq+1)
+			if diff < 0 {
+				break
+			}
+			if diff > 0 {
+				// Already consumed: tail is stale.
+				continue retry
+			}
+
+			o := Order{ID: rb.ids[offset], Price: rb.prices[offset], Qty: rb.qtys[offset]}
+			if cycleDiff(loadCycle(&rb.cycleState[offset]), seq+1) != 0 {
+				continue retry
+			}
+			out = append(out, o)
+		}
+		return out
+	}
+}
+
+// Range is ForEach without the sequence numbers: it calls fn with each
+// published item from readIndex to writeIndex, oldest first, without
+// consuming any, and stops early if fn returns false. It has the same
+// best-effort view as ForEach. fn runs with no lock held and may call back
+// into the buffer.
+func (rb *RingBuffer) Range(fn func(Order) bool) {
+	rb.ForEach(func(_ uint64, o Order) bool { return fn(o) })
+}
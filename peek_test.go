@@ -0,0 +1,34 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRangeMatchesForEach(t *testing.T) {
+	rb := Newbuffer(8)
+	for i := range uint64(6) {
+		rb.Enqueue(i, 0, 0)
+	}
+	rb.DequeueOrder()
+
+	var viaForEach, viaRange []uint64
+	rb.ForEach(func(_ uint64, o Order) bool {
+		viaForEach = append(viaForEach, o.ID)
+		return true
+	})
+	rb.Range(func(o Order) bool {
+		viaRange = append(viaRange, o.ID)
+		return len(viaRange) < 3
+	})
+
+	if want := []uint64{1, 2, 3, 4, 5}; !slices.Equal(viaForEach, want) {
+		t.Fatalf("ForEach saw %v, want %v", viaForEach, want)
+	}
+	if want := []uint64{1, 2, 3}; !slices.Equal(viaRange, want) {
+		t.Fatalf("Range saw %v, want %v", viaRange, want)
+	}
+	if rb.Len() != 5 {
+		t.Fatalf("Len() = %d after walking, want 5", rb.Len())
+	}
+}
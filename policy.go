@@ -0,0 +1,52 @@
+package main
+
+import "sync/atomic"
+
+// Policy decides what Enqueue does when the buffer is full.
+type Policy int
+
+const (
+	// PolicyReject makes Enqueue return false on a full buffer. It is the
+	// default.
+	PolicyReject Policy = iota
+
+	// PolicyOverwrite makes Enqueue discard the oldest unread item to make
+	// room, so producers always succeed and consumers see the newest data.
+	PolicyOverwrite
+)
+
+// NewbufferWithPolicy is Newbuffer with the given full-buffer policy.
+//
+// Overwrite mode is safe with any number of producers and consumers. A
+// producer that finds the buffer full drops the oldest item by dequeuing it
+// exactly as a consumer would, racing real consumers for readIndex with the
+// same CAS, so an item is either dropped or delivered, never both, and the
+// cycle states follow the usual lifecycle. Only the item occupying the slot
+// the producer needs is dropped; if that slot is still being written by
+// another producer or read by a consumer, the producer retries until that
+// operation finishes. Only Enqueue and the calls built on it
+// overwrite; the batch enqueues still fail on a full buffer.
+func NewbufferWithPolicy(capacity uint64, policy Policy) *RingBuffer {
+	return NewbufferOpts(capacity, WithPolicy(policy))
+}
+
+// Dropped returns how many items overwrite mode has discarded.
+func (rb *RingBuffer) Dropped() uint64 {
+	return atomic.LoadUint64(&rb.dropped)
+}
+
+// dropOldest discards the item one lap behind head, the one occupying the
+// slot head needs, if it is published and no consumer has claimed it yet.
+// Otherwise it does nothing and the caller retries.
+func (rb *RingBuffer) dropOldest(head uint64) {
+	tail := head - rb.capacity
+	offset := tail & rb.mask
+	if cycleDiff(loadCycle(&rb.cycleState[offset]), tail+1) != 0 {
+		return
+	}
+
+	if atomic.CompareAndSwapUint64(&rb.readIndex, tail, tail+1) {
+		storeCycle(&rb.cycleState[offset], tail+rb.capacity)
+		atomic.AddUint64(&rb.dropped, 1)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPolicyOverwrite(t *testing.T) {
+	rb := NewbufferWithPolicy(4, PolicyOverwrite)
+	for i := range uint64(10) {
+		if !rb.Enqueue(i, float64(i), uint32(i)) {
+			t.Fatalf("Enqueue(%d) failed in overwrite mode", i)
+		}
+	}
+	if rb.Dropped() != 6 {
+		t.Fatalf("Dropped() = %d, want 6", rb.Dropped())
+	}
+	for want := uint64(6); want < 10; want++ {
+		o, ok := rb.DequeueOrder()
+		if !ok || o != (Order{ID: want, Price: float64(want), Qty: uint32(want)}) {
+			t.Fatalf("DequeueOrder = %+v, %v, want ID %d", o, ok, want)
+		}
+	}
+	if _, ok := rb.DequeueOrder(); ok {
+		t.Fatal("dequeued more than the capacity")
+	}
+}
+
+func TestPolicyRejectDefault(t *testing.T) {
+	rb := Newbuffer(2)
+	rb.Enqueue(1, 0, 0)
+	rb.Enqueue(2, 0, 0)
+	if rb.Enqueue(3, 0, 0) || rb.Dropped() != 0 {
+		t.Fatalf("default policy overwrote: Dropped() = %d", rb.Dropped())
+	}
+}
+
+// TestPolicyOverwriteConcurrent runs several producers and consumers on a
+// small overwrite buffer. Every item must be either delivered exactly once or
+// dropped, never both, and each consumer must see each producer's items in
+// increasing order.
+func TestPolicyOverwriteConcurrent(t *testing.T) {
+	const producers, consumers, perProducer = 4, 4, 20_000
+	rb := NewbufferWithPolicy(8, PolicyOverwrite)
+
+	var pwg sync.WaitGroup
+	pwg.Add(producers)
+	for p := range uint64(producers) {
+		go func() {
+			defer pwg.Done()
+			for i := range uint64(perProducer) {
+				if !rb.Enqueue(p<<32|i, 0, 0) {
+					t.Errorf("Enqueue failed in overwrite mode")
+					return
+				}
+				if i%64 == 0 {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+
+	var done atomic.Bool
+	seen := make([][]uint64, consumers)
+	var cwg sync.WaitGroup
+	cwg.Add(consumers)
+	for c := range consumers {
+		go func() {
+			defer cwg.Done()
+			last := make([]int64, producers)
+			for i := range last {
+				last[i] = -1
+			}
+			var o Order
+			for {
+				if !rb.DequeueInto(&o) {
+					if done.Load() && rb.IsEmpty() {
+						return
+					}
+					runtime.Gosched()
+					continue
+				}
+				p, i := o.ID>>32, int64(o.ID&(1<<32-1))
+				if i <= last[p] {
+					t.Errorf("consumer %d: producer %d item %d after %d", c, p, i, last[p])
+				}
+				last[p] = i
+				seen[c] = append(seen[c], o.ID)
+			}
+		}()
+	}
+
+	pwg.Wait()
+	done.Store(true)
+	cwg.Wait()
+
+	delivered := make(map[uint64]bool)
+	for _, ids := range seen {
+		for _, id := range ids {
+			if delivered[id] {
+				t.Fatalf("item %#x delivered twice", id)
+			}
+			delivered[id] = true
+		}
+	}
+	if got := uint64(len(delivered)) + rb.Dropped(); got != producers*perProducer {
+		t.Fatalf("delivered %d + dropped %d = %d, want %d", len(delivered), rb.Dropped(), got, producers*perProducer)
+	}
+}
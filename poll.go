@@ -0,0 +1,54 @@
+package main
+
+// Result is what Poll found.
+type Result int
+
+const (
+	// Got means Poll dequeued an item.
+	Got Result = iota
+
+	// Empty means there was nothing to dequeue, but the buffer is still
+	// open or a consumer is still copying out a batch; try again later.
+	Empty
+
+	// ClosedEmpty means the buffer is closed and drained; the stream is
+	// over.
+	ClosedEmpty
+)
+
+func (r Result) String() string {
+	switch r {
+	case Got:
+		return "Got"
+	case Empty:
+		return "Empty"
+	case ClosedEmpty:
+		return "ClosedEmpty"
+	}
+	return "Result(?)"
+}
+
+// Poll makes one dequeue attempt and says why it failed, if it did, so a
+// consumer loop can tell "nothing yet" from "nothing ever again":
+//
+//	for {
+//		o, r := rb.Poll()
+//		switch r {
+//		case Got:
+//			handle(o)
+//		case Empty:
+//			runtime.Gosched()
+//		case ClosedEmpty:
+//			return
+//		}
+//	}
+func (rb *RingBuffer) Poll() (Order, Result) {
+	var o Order
+	if rb.Dequeue(&o.ID, &o.Price, &o.Qty) {
+		return o, Got
+	}
+	if rb.closedEmpty() {
+		return Order{}, ClosedEmpty
+	}
+	return Order{}, Empty
+}
@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestPoll(t *testing.T) {
+	rb := Newbuffer(8)
+	if _, r := rb.Poll(); r != Empty {
+		t.Fatalf("Poll() on an open, empty buffer = %v, want Empty", r)
+	}
+
+	rb.Enqueue(1, 1.5, 2)
+	if o, r := rb.Poll(); r != Got || o != (Order{ID: 1, Price: 1.5, Qty: 2}) {
+		t.Fatalf("Poll() = %+v, %v, want the item and Got", o, r)
+	}
+
+	rb.Enqueue(2, 0, 0)
+	rb.Close()
+	if o, r := rb.Poll(); r != Got || o.ID != 2 {
+		t.Fatalf("Poll() on a closed buffer with an item = %+v, %v, want item 2 and Got", o, r)
+	}
+	if _, r := rb.Poll(); r != ClosedEmpty {
+		t.Fatalf("Poll() on a closed, drained buffer = %v, want ClosedEmpty", r)
+	}
+
+	closed := Newbuffer(8)
+	closed.Close()
+	if _, r := closed.Poll(); r != ClosedEmpty {
+		t.Fatalf("Poll() on a buffer closed empty = %v, want ClosedEmpty", r)
+	}
+}
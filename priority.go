@@ -0,0 +1,60 @@
+package main
+
+// PriorityBuffer approximates highest-price-first delivery with a fixed set of
+// lanes, one RingBuffer each. Enqueue routes an order to the lane chosen by
+// the bucketing function, higher lanes meaning higher priority, and Dequeue
+// takes from the highest lane that has something ready. Within a lane the
+// order is FIFO, so two prices in the same bucket come out in arrival order,
+// and a lower-lane order can still be taken ahead of a higher-lane one that
+// arrives after Dequeue has scanned past its lane. Under steady high-priority
+// load the low lanes can starve.
+type PriorityBuffer struct {
+	lanes  []*RingBuffer
+	bucket func(price float64) int
+}
+
+// NewPriorityBuffer returns a buffer with numLanes lanes of laneCapacity
+// items each, which must be a power of two. bucket maps a price to a lane in
+// [0, numLanes); results outside that range are clamped.
+func NewPriorityBuffer(numLanes int, laneCapacity uint64, bucket func(price float64) int) *PriorityBuffer {
+	if numLanes < 1 {
+		panic("ring: PriorityBuffer needs at least one lane")
+	}
+
+	lanes := make([]*RingBuffer, numLanes)
+	for i := range lanes {
+		lanes[i] = Newbuffer(laneCapacity)
+	}
+	return &PriorityBuffer{lanes: lanes, bucket: bucket}
+}
+
+// LinearBuckets splits [lo, hi) into numLanes equal price bands, lowest band
+// in lane 0. Prices below lo land in lane 0 and prices at or above hi in the
+// top lane.
+func LinearBuckets(lo, hi float64, numLanes int) func(price float64) int {
+	width := (hi - lo) / float64(numLanes)
+	return func(price float64) int {
+		if price < lo {
+			return 0
+		}
+		return min(int((price-lo)/width), numLanes-1)
+	}
+}
+
+// Enqueue adds the order to its price lane, returning false if that lane is
+// full.
+func (pb *PriorityBuffer) Enqueue(id uint64, price float64, qty uint32) bool {
+	lane := min(max(pb.bucket(price), 0), len(pb.lanes)-1)
+	return pb.lanes[lane].Enqueue(id, price, qty)
+}
+
+// Dequeue takes the oldest order from the highest non-empty lane, returning
+// false only if every lane is empty.
+func (pb *PriorityBuffer) Dequeue(id *uint64, price *float64, qty *uint32) bool {
+	for i := len(pb.lanes) - 1; i >= 0; i-- {
+		if pb.lanes[i].Dequeue(id, price, qty) {
+			return true
+		}
+	}
+	return false
+}
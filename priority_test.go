@@ -0,0 +1,103 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLinearBuckets(t *testing.T) {
+	bucket := LinearBuckets(100, 200, 4)
+	tests := []struct {
+		price float64
+		lane  int
+	}{
+		{50, 0}, {100, 0}, {124.9, 0}, {125, 1}, {160, 2}, {199.9, 3}, {200, 3}, {1e9, 3},
+	}
+	for _, tt := range tests {
+		if got := bucket(tt.price); got != tt.lane {
+			t.Errorf("bucket(%v) = %d, want %d", tt.price, got, tt.lane)
+		}
+	}
+}
+
+// TestPriorityBufferOrder fills the low lanes before the high one and checks
+// that Dequeue still returns every high-lane order first, FIFO within a lane.
+func TestPriorityBufferOrder(t *testing.T) {
+	pb := NewPriorityBuffer(3, 8, LinearBuckets(0, 300, 3))
+	enqueue := []Order{{1, 10, 0}, {2, 150, 0}, {3, 20, 0}, {4, 250, 0}, {5, 160, 0}, {6, 290, 0}}
+	for _, o := range enqueue {
+		if !pb.Enqueue(o.ID, o.Price, o.Qty) {
+			t.Fatalf("Enqueue(%d) failed", o.ID)
+		}
+	}
+
+	var got []uint64
+	var o Order
+	for pb.Dequeue(&o.ID, &o.Price, &o.Qty) {
+		got = append(got, o.ID)
+	}
+	want := []uint64{4, 6, 2, 5, 1, 3}
+	for i := range want {
+		if i >= len(got) || got[i] != want[i] {
+			t.Fatalf("dequeue order %v, want %v", got, want)
+		}
+	}
+}
+
+// TestPriorityBufferUnderLoad runs a high-price and a low-price producer
+// against one consumer with small lanes, so both stay busy. Whenever the
+// consumer gets a low-lane order although a high-lane order had been
+// enqueued before the call and not yet taken, the high lane was skipped: that
+// must never happen.
+func TestPriorityBufferUnderLoad(t *testing.T) {
+	const perProducer = 5_000
+	pb := NewPriorityBuffer(2, 16, LinearBuckets(0, 200, 2))
+
+	var highEnqueued atomic.Uint64
+	var wg sync.WaitGroup
+	wg.Add(2)
+	for _, price := range []float64{50, 150} {
+		go func() {
+			defer wg.Done()
+			for i := range uint64(perProducer) {
+				for !pb.Enqueue(i, price, 0) {
+					runtime.Gosched()
+				}
+				if price > 100 {
+					highEnqueued.Add(1)
+				}
+			}
+		}()
+	}
+
+	var high, low, inversions uint64
+	var o Order
+	for high+low < 2*perProducer {
+		ready := highEnqueued.Load()
+		if !pb.Dequeue(&o.ID, &o.Price, &o.Qty) {
+			runtime.Gosched()
+			continue
+		}
+		if o.Price > 100 {
+			if o.ID != high {
+				t.Fatalf("high lane item %d, want %d", o.ID, high)
+			}
+			high++
+			continue
+		}
+		if o.ID != low {
+			t.Fatalf("low lane item %d, want %d", o.ID, low)
+		}
+		low++
+		if ready > high {
+			inversions++
+		}
+	}
+	wg.Wait()
+
+	if inversions > 0 {
+		t.Fatalf("%d low-lane orders taken while a high-lane order was waiting", inversions)
+	}
+}
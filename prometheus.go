@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+)
+
+// WritePrometheus writes the buffer's gauges and counters in the Prometheus
+// text exposition format, each labelled buffer="name". Pending and capacity
+// come from Snapshot and are always present; the operation counters come from
+// Stats and stay at zero unless the buffer was built with metrics. Writing the
+// format directly keeps the package free of the Prometheus client library;
+// any Prometheus server can scrape the output. Builds with the prometheus tag
+// also get Collector, for registering with a prometheus.Registerer.
+func (rb *RingBuffer) WritePrometheus(w io.Writer, name string) error {
+	return writePrometheus(w, map[string]*RingBuffer{name: rb})
+}
+
+// PrometheusHandler serves the metrics of every buffer in buffers, keyed by
+// the name used as their label, on each scrape.
+func PrometheusHandler(buffers map[string]*RingBuffer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writePrometheus(w, buffers)
+	})
+}
+
+type promMetric struct {
+	name, kind, help string
+	value            func(s BufferState, st Stats, rb *RingBuffer) uint64
+}
+
+var promMetrics = []promMetric{
+	{"ring_pending_items", "gauge", "Items claimed by producers and not yet claimed by consumers.",
+		func(s BufferState, _ Stats, _ *RingBuffer) uint64 { return s.Pending }},
+	{"ring_capacity_items", "gauge", "Buffer capacity.",
+		func(s BufferState, _ Stats, _ *RingBuffer) uint64 { return s.Capacity }},
+	{"ring_high_water_items", "gauge", "Most items held at once since the last ResetHighWaterMark.",
+		func(_ BufferState, _ Stats, rb *RingBuffer) uint64 { return rb.HighWaterMark() }},
+	{"ring_enqueued_total", "counter", "Items enqueued.",
+		func(_ BufferState, st Stats, _ *RingBuffer) uint64 { return st.Enqueued }},
+	{"ring_dequeued_total", "counter", "Items dequeued.",
+		func(_ BufferState, st Stats, _ *RingBuffer) uint64 { return st.Dequeued }},
+	{"ring_full_total", "counter", "Enqueue calls rejected because the buffer was full.",
+		func(_ BufferState, st Stats, _ *RingBuffer) uint64 { return st.FullAttempts }},
+	{"ring_empty_total", "counter", "Dequeue calls that found the buffer empty.",
+		func(_ BufferState, st Stats, _ *RingBuffer) uint64 { return st.EmptyAttempts }},
+	{"ring_enqueue_cas_retries_total", "counter", "Retried claims on writeIndex.",
+		func(_ BufferState, st Stats, _ *RingBuffer) uint64 { return st.EnqueueRetries }},
+	{"ring_dequeue_cas_retries_total", "counter", "Retried claims on readIndex.",
+		func(_ BufferState, st Stats, _ *RingBuffer) uint64 { return st.DequeueRetries }},
+	{"ring_write_cas_failures_total", "counter", "Lost CAS races on writeIndex, excluding stale-index retries.",
+		func(_ BufferState, st Stats, _ *RingBuffer) uint64 { return st.WriteCASFailures }},
+	{"ring_read_cas_failures_total", "counter", "Lost CAS races on readIndex, excluding stale-index retries.",
+		func(_ BufferState, st Stats, _ *RingBuffer) uint64 { return st.ReadCASFailures }},
+	{"ring_dropped_total", "counter", "Items discarded by the overwrite policy.",
+		func(_ BufferState, _ Stats, rb *RingBuffer) uint64 { return rb.Dropped() }},
+}
+
+func writePrometheus(w io.Writer, buffers map[string]*RingBuffer) error {
+	names := make([]string, 0, len(buffers))
+	for name := range buffers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	states := make([]BufferState, len(names))
+	stats := make([]Stats, len(names))
+	for i, name := range names {
+		states[i] = buffers[name].Snapshot()
+		stats[i] = buffers[name].Stats()
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, m := range promMetrics {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for i, name := range names {
+			fmt.Fprintf(bw, "%s{buffer=%q} %d\n", m.name, name, m.value(states[i], stats[i], buffers[name]))
+		}
+	}
+	return bw.Flush()
+}
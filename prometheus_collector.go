@@ -0,0 +1,76 @@
+//go:build prometheus
+
+package main
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector exports buffers through the Prometheus client library, for
+// programs that already serve a prometheus.Registry. It reports the same
+// metrics as WritePrometheus, built from the same table, so the two never
+// disagree on names or help text. It is behind the prometheus build tag so the
+// package stays free of the dependency by default:
+//
+//	go build -tags prometheus .
+type Collector struct {
+	mu      sync.Mutex
+	buffers map[string]*RingBuffer
+	descs   []*prometheus.Desc
+}
+
+// NewCollector returns a Collector over buffers, keyed by the name used as
+// their buffer label. The map is copied; Add registers more buffers later.
+func NewCollector(buffers map[string]*RingBuffer) *Collector {
+	c := &Collector{buffers: make(map[string]*RingBuffer, len(buffers))}
+	for name, rb := range buffers {
+		c.buffers[name] = rb
+	}
+	for _, m := range promMetrics {
+		c.descs = append(c.descs, prometheus.NewDesc(m.name, m.help, []string{"buffer"}, nil))
+	}
+	return c
+}
+
+// RegisterPrometheus registers a Collector over buffers with reg and returns
+// it, so that prometheus.MustRegister-style setups need one call.
+func RegisterPrometheus(reg prometheus.Registerer, buffers map[string]*RingBuffer) (*Collector, error) {
+	c := NewCollector(buffers)
+	if err := reg.Register(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Add starts exporting rb under name, replacing any buffer of that name.
+func (c *Collector) Add(name string, rb *RingBuffer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.buffers[name] = rb
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector. Each buffer's Snapshot and Stats
+// are read once per scrape.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name, rb := range c.buffers {
+		state, stats := rb.Snapshot(), rb.Stats()
+		for i, m := range promMetrics {
+			kind := prometheus.GaugeValue
+			if m.kind == "counter" {
+				kind = prometheus.CounterValue
+			}
+			ch <- prometheus.MustNewConstMetric(c.descs[i], kind, float64(m.value(state, stats, rb)), name)
+		}
+	}
+}
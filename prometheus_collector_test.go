@@ -0,0 +1,100 @@
+//go:build prometheus
+
+package main
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+)
+
+// TestCollector registers two buffers, scrapes the registry over HTTP and
+// parses the text it serves, checking a gauge and a counter per buffer.
+func TestCollector(t *testing.T) {
+	orders := NewbufferWithMetrics(8)
+	for i := range uint64(3) {
+		orders.Enqueue(i, 0, 0)
+	}
+	orders.DequeueOrder()
+	quotes := Newbuffer(16)
+
+	reg := prometheus.NewRegistry()
+	c, err := RegisterPrometheus(reg, map[string]*RingBuffer{"orders": orders})
+	if err != nil {
+		t.Fatalf("RegisterPrometheus: %v", err)
+	}
+	c.Add("quotes", quotes)
+	if _, err := RegisterPrometheus(reg, nil); err == nil {
+		t.Fatal("registering a second Collector with the same metrics succeeded")
+	}
+
+	rec := httptest.NewRecorder()
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{}).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	parser := expfmt.NewTextParser(model.UTF8Validation)
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(rec.Body.Bytes()))
+	if err != nil {
+		t.Fatalf("parsing the scrape: %v\n%s", err, rec.Body.String())
+	}
+	if len(families) != len(promMetrics) {
+		t.Errorf("scrape has %d metric families, want %d", len(families), len(promMetrics))
+	}
+
+	value := func(metric, buffer string) float64 {
+		t.Helper()
+		f, ok := families[metric]
+		if !ok {
+			t.Fatalf("metric %s missing", metric)
+		}
+		for _, m := range f.GetMetric() {
+			if m.GetLabel()[0].GetValue() == buffer {
+				if m.GetCounter() != nil {
+					return m.GetCounter().GetValue()
+				}
+				return m.GetGauge().GetValue()
+			}
+		}
+		t.Fatalf("metric %s has no buffer=%q", metric, buffer)
+		return 0
+	}
+	checks := []struct {
+		metric, buffer string
+		want           float64
+	}{
+		{"ring_pending_items", "orders", 2},
+		{"ring_capacity_items", "orders", 8},
+		{"ring_enqueued_total", "orders", 3},
+		{"ring_dequeued_total", "orders", 1},
+		{"ring_capacity_items", "quotes", 16},
+		{"ring_enqueued_total", "quotes", 0},
+	}
+	for _, c := range checks {
+		if got := value(c.metric, c.buffer); got != c.want {
+			t.Errorf("%s{buffer=%q} = %v, want %v", c.metric, c.buffer, got, c.want)
+		}
+	}
+	if got := families["ring_enqueued_total"].GetType().String(); got != "COUNTER" {
+		t.Errorf("ring_enqueued_total has type %s, want COUNTER", got)
+	}
+}
+
+// TestWritePrometheusParses checks that the dependency-free exporter's output
+// is accepted by the client library's parser too.
+func TestWritePrometheusParses(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewbufferWithMetrics(8).WritePrometheus(&buf, "orders"); err != nil {
+		t.Fatalf("WritePrometheus: %v", err)
+	}
+	parser := expfmt.NewTextParser(model.UTF8Validation)
+	families, err := parser.TextToMetricFamilies(&buf)
+	if err != nil {
+		t.Fatalf("parsing WritePrometheus output: %v", err)
+	}
+	if len(families) != len(promMetrics) {
+		t.Errorf("output has %d metric families, want %d", len(families), len(promMetrics))
+	}
+}
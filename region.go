@@ -0,0 +1,55 @@
+package main
+
+import "sync/atomic"
+
+// ProcessRegion claims every published item at readIndex, up to maxItems,
+// and hands them to fn as slices of the buffer's own columns, so bulk or
+// vectorized work on them needs no copy. A run that crosses the end of the
+// arrays arrives as two calls, oldest first. Once fn has returned the slots
+// go back to producers. ProcessRegion returns how many items it took, and
+// takes none if nothing is ready.
+//
+// The claim is a CAS on readIndex like DequeueBatch, so other consumers may
+// run concurrently and never see these items. What fn gets is unsafe in the
+// usual zero-copy way: the slices alias slots that producers reuse as soon as
+// ProcessRegion returns, so fn must not keep them, and it must not call back
+// into the buffer's consuming methods, which could wait on the slots it holds.
+// fn may write to the slices.
+func (rb *RingBuffer) ProcessRegion(maxItems int, fn func(ids []uint64, prices []float64, qtys []uint32)) uint64 {
+	limit := min(uint64(max(maxItems, 0)), rb.capacity)
+	if limit == 0 {
+		return 0
+	}
+
+	var tail, count uint64
+	for {
+		tail = atomic.LoadUint64(&rb.readIndex)
+		var stale bool
+		count, stale = rb.readable(tail, limit)
+		if stale {
+			rb.stats.addDequeueRetry()
+			continue
+		}
+		if count == 0 {
+			rb.stats.addEmpty()
+			return 0
+		}
+		if atomic.CompareAndSwapUint64(&rb.readIndex, tail, tail+count) {
+			break
+		}
+		rb.stats.addReadCASFailure()
+	}
+
+	start := tail & rb.mask
+	first := min(count, rb.capacity-start)
+	fn(rb.ids[start:start+first], rb.prices[start:start+first], rb.qtys[start:start+first])
+	if rest := count - first; rest > 0 {
+		fn(rb.ids[:rest], rb.prices[:rest], rb.qtys[:rest])
+	}
+
+	for seq := tail; seq != tail+count; seq++ {
+		storeCycle(&rb.cycleState[seq&rb.mask], seq+rb.capacity)
+	}
+	rb.stats.addDequeued(count)
+	return count
+}
@@ -0,0 +1,94 @@
+package main
+
+import "sync/atomic"
+
+// Resize moves the pending items into new arrays of newCapacity slots, keeping
+// their order and sequence numbers. It is not lock-free and does no locking:
+// the caller must make sure no other goroutine touches the buffer until it
+// returns. A claimed but unpublished slot, the trace of an operation still in
+// progress, makes it fail with ErrResizeBusy and leaves the buffer unchanged.
+// Shrinking works as long as the pending items still fit. Slices from
+// GetBatchBuffers are sized for the new capacity afterwards. With
+// WithBackingProvider the new arrays come from the provider and the old ones
+// go back to it.
+func (rb *RingBuffer) Resize(newCapacity uint64) error {
+	if !validCapacity(newCapacity) {
+		return rb.newError("Resize", newCapacity, ErrInvalidCapacity)
+	}
+	// The spillover reconciler is the one goroutine the caller cannot stop;
+	// holding its lock keeps it out until the new arrays are in place.
+	if rb.spill != nil {
+		rb.spill.mu.Lock()
+		defer rb.spill.mu.Unlock()
+	}
+
+	tail := atomic.LoadUint64(&rb.readIndex)
+	head := atomic.LoadUint64(&rb.writeIndex)
+	pending := head - tail
+	if pending > newCapacity {
+		return rb.newError("Resize", newCapacity, ErrResizeTooSmall)
+	}
+	if n, _ := rb.readable(tail, pending); n != pending {
+		return rb.newError("Resize", newCapacity, ErrResizeBusy)
+	}
+
+	mask := newCapacity - 1
+	next := allocBacking(rb.backing, newCapacity)
+	cycleState, ids, prices, qtys := next.CycleState, next.IDs, next.Prices, next.Qtys
+
+	// The new ring covers sequences tail to tail+newCapacity-1, each slot
+	// exactly once: published for the pending items, free for the rest.
+	for seq := tail; seq < tail+newCapacity; seq++ {
+		offset := seq & mask
+		if seq < head {
+			src := seq & rb.mask
+			ids[offset] = rb.ids[src]
+			prices[offset] = rb.prices[src]
+			qtys[offset] = rb.qtys[src]
+			cycleState[offset] = cycle(seq + 1)
+		} else {
+			cycleState[offset] = cycle(seq)
+		}
+	}
+
+	old := rb.Backing()
+	rb.capacity = newCapacity
+	rb.mask = mask
+	rb.cycleState = cycleState
+	rb.ids = ids
+	rb.prices = prices
+	rb.qtys = qtys
+	rb.scratch = newScratchPool(rb.scratch.batch, newCapacity)
+	if rb.backing != nil {
+		rb.backing.Release(old)
+	}
+	return nil
+}
+
+// Reset empties the buffer for reuse without reallocating: both indices go
+// back to 0, every cycle state is reseeded exactly as Newbuffer does, and the
+// closed flag, the overwrite drop count, the Stats counters, the operation
+// log and the spillover queue are cleared. Channels returned by Done before
+// the reset no longer belong to the buffer: their watcher is stopped and they
+// are never closed. Old payloads stay in the columns but are unreachable.
+// Like Resize it does no locking, and the caller must make sure no other
+// goroutine touches the buffer until it returns.
+func (rb *RingBuffer) Reset() {
+	rb.spill.cancel()
+	rb.done.cancel()
+	atomic.StoreUint64(&rb.writeIndex, 0)
+	atomic.StoreUint64(&rb.readIndex, 0)
+	initCycleState(rb.cycleState)
+
+	atomic.StoreUint32(&rb.closed, 0)
+	rb.done = newDoneSignal()
+	atomic.StoreUint64(&rb.dropped, 0)
+	if rb.stats != nil {
+		*rb.stats = bufferStats{contention: rb.stats.contention}
+	}
+	rb.oplog.clear()
+	if rb.spill != nil {
+		rb.spill = newSpillover(rb.spill.limit)
+		go rb.spill.run(rb)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestResizeGrow checks that pending items survive a grow from 8 to 32 with
+// their order and values, including when they wrap around the old arrays,
+// and that the extra room is usable afterwards.
+func TestResizeGrow(t *testing.T) {
+	rb := Newbuffer(8)
+	for i := range uint64(5) {
+		rb.Enqueue(i, 0, 0)
+		rb.DequeueOrder()
+	}
+	for i := range uint64(8) {
+		rb.Enqueue(i, float64(i)+0.5, uint32(i)*10)
+	}
+
+	if err := rb.Resize(32); err != nil {
+		t.Fatalf("Resize(32): %v", err)
+	}
+	if rb.Cap() != 32 || rb.Len() != 8 {
+		t.Fatalf("after Resize: Cap() = %d, Len() = %d, want 32 and 8", rb.Cap(), rb.Len())
+	}
+	for i := range uint64(24) {
+		if !rb.Enqueue(8+i, float64(8+i)+0.5, uint32(8+i)*10) {
+			t.Fatalf("Enqueue %d into the grown buffer failed", i)
+		}
+	}
+	if rb.Enqueue(99, 0, 0) {
+		t.Fatal("Enqueue past the new capacity succeeded")
+	}
+	for i := range uint64(32) {
+		o, ok := rb.DequeueOrder()
+		if !ok || o != (Order{ID: i, Price: float64(i) + 0.5, Qty: uint32(i) * 10}) {
+			t.Fatalf("item %d = %+v, %v", i, o, ok)
+		}
+	}
+}
+
+func TestResizeShrink(t *testing.T) {
+	rb := Newbuffer(32)
+	for i := range uint64(3) {
+		rb.Enqueue(i, 0, 0)
+	}
+	if err := rb.Resize(2); !errors.Is(err, ErrResizeTooSmall) {
+		t.Fatalf("Resize(2) with 3 pending = %v, want ErrResizeTooSmall", err)
+	}
+	if err := rb.Resize(12); !errors.Is(err, ErrInvalidCapacity) {
+		t.Fatalf("Resize(12) = %v, want ErrInvalidCapacity", err)
+	}
+	if rb.Cap() != 32 {
+		t.Fatalf("failed Resize changed the capacity to %d", rb.Cap())
+	}
+	if err := rb.Resize(4); err != nil {
+		t.Fatalf("Resize(4): %v", err)
+	}
+	for i := range uint64(3) {
+		if o, ok := rb.DequeueOrder(); !ok || o.ID != i {
+			t.Fatalf("item %d = %+v, %v", i, o, ok)
+		}
+	}
+}
+
+// TestResizeBusy checks that a claimed but unpublished slot makes Resize fail
+// and leaves the buffer as it was.
+func TestResizeBusy(t *testing.T) {
+	rb := Newbuffer(8)
+	rb.Enqueue(1, 0, 0)
+	seq, _ := rb.Claim()
+	if err := rb.Resize(16); !errors.Is(err, ErrResizeBusy) {
+		t.Fatalf("Resize with a claimed slot = %v, want ErrResizeBusy", err)
+	}
+	rb.Publish(seq)
+	if err := rb.Resize(16); err != nil {
+		t.Fatalf("Resize after Publish: %v", err)
+	}
+	if rb.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", rb.Len())
+	}
+}
+
+// TestReset checks that Reset leaves the buffer exactly as Newbuffer would,
+// without reallocating its arrays.
+func TestReset(t *testing.T) {
+	rb := NewbufferOpts(8, WithMetrics(true), WithPolicy(PolicyOverwrite))
+	for i := range uint64(20) {
+		rb.Enqueue(i, 0, 0)
+	}
+	rb.DequeueOrder()
+	rb.Close()
+	ids := &rb.ids[0]
+
+	rb.Reset()
+	fresh := Newbuffer(8)
+	if rb.writeIndex != 0 || rb.readIndex != 0 {
+		t.Fatalf("indices %d, %d after Reset, want 0", rb.writeIndex, rb.readIndex)
+	}
+	for i := range rb.cycleState {
+		if rb.cycleState[i] != fresh.cycleState[i] {
+			t.Fatalf("cycleState[%d] = %d after Reset, want %d", i, rb.cycleState[i], fresh.cycleState[i])
+		}
+	}
+	if &rb.ids[0] != ids {
+		t.Fatal("Reset reallocated the arrays")
+	}
+	if rb.Closed() || rb.Dropped() != 0 || rb.Stats() != (Stats{}) {
+		t.Fatalf("after Reset: Closed() = %v, Dropped() = %d, Stats() = %+v", rb.Closed(), rb.Dropped(), rb.Stats())
+	}
+
+	// The options survive: the policy still overwrites.
+	for i := range uint64(10) {
+		rb.Enqueue(100+i, 0, 0)
+	}
+	if o, ok := rb.DequeueOrder(); !ok || o.ID != 102 {
+		t.Fatalf("first item after Reset = %+v, %v, want 102", o, ok)
+	}
+}
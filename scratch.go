@@ -0,0 +1,68 @@
+package main
+
+import "sync"
+
+// defaultBatchBuffers is the length of the slices GetBatchBuffers hands out
+// unless WithBatchBuffers says otherwise.
+const defaultBatchBuffers = 64
+
+// scratchPool recycles the ids/prices/qtys slices batch callers need. The
+// slices live in batchSlices holders; once a holder's slices are handed out
+// the empty holder goes to a second pool, so that a Get/Put cycle allocates
+// nothing in the steady state.
+type scratchPool struct {
+	batch   int // the length asked for with WithBatchBuffers
+	size    int // batch, capped at the buffer's capacity
+	full    sync.Pool
+	holders sync.Pool
+}
+
+// newScratchPool returns a pool of batch-length slices for a buffer of the
+// given capacity. A batch can never be longer than the buffer, so the length
+// is capped at capacity; Resize builds a new pool for the new capacity.
+func newScratchPool(batch int, capacity uint64) *scratchPool {
+	return &scratchPool{batch: batch, size: int(min(uint64(batch), capacity))}
+}
+
+type batchSlices struct {
+	ids    []uint64
+	prices []float64
+	qtys   []uint32
+}
+
+// GetBatchBuffers returns scratch slices of the buffer's batch length, taken
+// from a pool when one is available, for use with EnqueueBatch, DequeueBatch
+// and the other batch calls:
+//
+//	ids, prices, qtys := rb.GetBatchBuffers()
+//	defer rb.PutBatchBuffers(ids, prices, qtys)
+//	n := rb.DequeueBatchUpTo(ids, prices, qtys)
+//
+// Their contents are whatever the last user left in them.
+func (rb *RingBuffer) GetBatchBuffers() ([]uint64, []float64, []uint32) {
+	p := rb.scratch
+	if h, ok := p.full.Get().(*batchSlices); ok {
+		ids, prices, qtys := h.ids, h.prices, h.qtys
+		*h = batchSlices{}
+		p.holders.Put(h)
+		return ids, prices, qtys
+	}
+	return make([]uint64, p.size), make([]float64, p.size), make([]uint32, p.size)
+}
+
+// PutBatchBuffers returns slices obtained from GetBatchBuffers to the pool.
+// They must not be used afterwards. Slices too short for the buffer's batch
+// length are dropped rather than pooled, so resliced ones are fine to pass.
+func (rb *RingBuffer) PutBatchBuffers(ids []uint64, prices []float64, qtys []uint32) {
+	p := rb.scratch
+	if cap(ids) < p.size || cap(prices) < p.size || cap(qtys) < p.size {
+		return
+	}
+
+	h, ok := p.holders.Get().(*batchSlices)
+	if !ok {
+		h = new(batchSlices)
+	}
+	*h = batchSlices{ids: ids[:p.size], prices: prices[:p.size], qtys: qtys[:p.size]}
+	p.full.Put(h)
+}
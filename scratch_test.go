@@ -0,0 +1,51 @@
+package main
+
+import "testing"
+
+// TestBatchBuffersFollowResize checks that the scratch slices track the
+// capacity: after shrinking below the batch length they must still be
+// usable with DequeueBatch, and growing back restores the full length.
+func TestBatchBuffersFollowResize(t *testing.T) {
+	rb := Newbuffer(1024)
+	ids, prices, qtys := rb.GetBatchBuffers()
+	if len(ids) != defaultBatchBuffers {
+		t.Fatalf("len = %d, want %d", len(ids), defaultBatchBuffers)
+	}
+	rb.PutBatchBuffers(ids, prices, qtys)
+
+	if err := rb.Resize(16); err != nil {
+		t.Fatalf("Resize(16): %v", err)
+	}
+	ids, prices, qtys = rb.GetBatchBuffers()
+	if len(ids) != 16 || len(prices) != 16 || len(qtys) != 16 {
+		t.Fatalf("after Resize(16): lens %d, %d, %d, want 16", len(ids), len(prices), len(qtys))
+	}
+	if n := rb.EnqueueBatch(ids, prices, qtys); n != 16 {
+		t.Fatalf("EnqueueBatch = %d, want 16", n)
+	}
+	if n := rb.DequeueBatch(ids, prices, qtys); n != 16 {
+		t.Fatalf("DequeueBatch = %d, want 16", n)
+	}
+	rb.PutBatchBuffers(ids, prices, qtys)
+
+	if err := rb.Resize(1024); err != nil {
+		t.Fatalf("Resize(1024): %v", err)
+	}
+	if ids, _, _ := rb.GetBatchBuffers(); len(ids) != defaultBatchBuffers {
+		t.Fatalf("after Resize(1024): len = %d, want %d", len(ids), defaultBatchBuffers)
+	}
+}
+
+func TestBatchBuffersFollowUnmarshal(t *testing.T) {
+	data, err := Newbuffer(8).MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+	rb := Newbuffer(1024)
+	if err := rb.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+	if ids, _, _ := rb.GetBatchBuffers(); len(ids) != 8 {
+		t.Fatalf("len = %d, want 8", len(ids))
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"runtime"
+	"time"
+)
+
+// selfTestItems is how many items WithStartupSelfTest moves, enough to
+// measure a rate in well under a millisecond on a healthy machine.
+const selfTestItems = 1 << 16
+
+// SelfTestReport is what WithStartupSelfTest measured.
+type SelfTestReport struct {
+	Items      int
+	Elapsed    time.Duration
+	OpsPerSec  float64
+	GOMAXPROCS int
+
+	// BelowFloor is set when OpsPerSec came in under the floor passed to
+	// WithStartupSelfTest.
+	BelowFloor bool
+}
+
+// runSelfTest moves selfTestItems from one producer goroutine to the calling
+// goroutine through a scratch buffer with the configured wait strategies, so
+// the buffer being built starts out untouched and its Stats stay at zero.
+// The scratch buffer is at most 1024 slots, so a huge buffer isn't allocated
+// twice.
+func runSelfTest(capacity uint64, cfg bufferConfig) SelfTestReport {
+	rb := NewbufferOpts(min(capacity, 1024), WithWaitStrategy(cfg.wait), WithSpinWait(cfg.spinWait))
+
+	start := time.Now()
+	go func() {
+		for i := uint64(0); i < selfTestItems; i++ {
+			rb.EnqueueBlocking(i, 0, 0)
+		}
+	}()
+	for range selfTestItems {
+		rb.DequeueBlocking()
+	}
+	elapsed := time.Since(start)
+
+	ops := float64(selfTestItems) / max(elapsed.Seconds(), 1e-9)
+	return SelfTestReport{
+		Items:      selfTestItems,
+		Elapsed:    elapsed,
+		OpsPerSec:  ops,
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		BelowFloor: ops < cfg.selfTest.floor,
+	}
+}
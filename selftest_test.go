@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestStartupSelfTest(t *testing.T) {
+	var got []SelfTestReport
+	report := func(r SelfTestReport) { got = append(got, r) }
+
+	rb := NewbufferOpts(1<<20, WithMetrics(true), WithStartupSelfTest(1, report))
+	if len(got) != 1 {
+		t.Fatalf("report called %d times, want once", len(got))
+	}
+	r := got[0]
+	t.Logf("self-test: %+v", r)
+	if r.Items != selfTestItems || r.Elapsed <= 0 || r.GOMAXPROCS < 1 {
+		t.Fatalf("implausible report %+v", r)
+	}
+	// Even the race detector on one slow core manages far more than 1000
+	// items per second, and nothing can move more than one per nanosecond.
+	if r.OpsPerSec < 1e3 || r.OpsPerSec > 1e9 || r.BelowFloor {
+		t.Fatalf("implausible rate %.0f ops/sec, BelowFloor %v", r.OpsPerSec, r.BelowFloor)
+	}
+	if st := rb.Stats(); st != (Stats{}) || !rb.IsEmpty() {
+		t.Fatalf("self-test touched the new buffer: Stats() = %+v, Len() = %d", st, rb.Len())
+	}
+
+	NewbufferOpts(8, WithStartupSelfTest(1e15, report))
+	if len(got) != 2 || !got[1].BelowFloor {
+		t.Fatalf("report against an unreachable floor = %+v, want BelowFloor", got[1:])
+	}
+}
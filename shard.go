@@ -0,0 +1,92 @@
+package main
+
+import "math/rand/v2"
+
+// RoundRobinEnqueue offers o to each buffer in turn and returns true as soon
+// as one accepts it. It returns false only if every buffer is full. Like
+// ShardedBuffer it starts at a random buffer from the runtime's per-thread
+// generator, so concurrent callers spread over the buffers without sharing a
+// rotation counter.
+func RoundRobinEnqueue(bufs []*RingBuffer, o Order) bool {
+	n := uint64(len(bufs))
+	if n == 0 {
+		return false
+	}
+
+	start := rand.Uint64N(n)
+	for i := uint64(0); i < n; i++ {
+		if bufs[(start+i)%n].Enqueue(o.ID, o.Price, o.Qty) {
+			return true
+		}
+	}
+	return false
+}
+
+// ShardedBuffer spreads items over several independent RingBuffers so that
+// producers CAS different writeIndex lines instead of all fighting over one.
+// Each call starts at a random shard, drawn from the runtime's per-thread
+// generator so the routing itself shares no state, and moves on to the next
+// shard if that one is full or empty.
+//
+// There is no global order. Items in the same shard stay FIFO, but two items
+// from one producer can land in different shards and be dequeued in either
+// order. Random starting shards keep consumers from favouring any shard, so
+// no shard is left behind for long under steady load, but nothing bounds how
+// long a given item waits relative to items in other shards.
+type ShardedBuffer struct {
+	shards []*RingBuffer
+}
+
+// NewShardedBuffer returns a buffer of numShards shards, each holding
+// perShardCapacity items, which must be a power of two.
+func NewShardedBuffer(numShards int, perShardCapacity uint64) *ShardedBuffer {
+	if numShards < 1 {
+		panic("ring: ShardedBuffer needs at least one shard")
+	}
+
+	shards := make([]*RingBuffer, numShards)
+	for i := range shards {
+		shards[i] = Newbuffer(perShardCapacity)
+	}
+	return &ShardedBuffer{shards: shards}
+}
+
+// Enqueue adds the item to some shard with room, returning false only if
+// every shard is full.
+func (sb *ShardedBuffer) Enqueue(id uint64, price float64, qty uint32) bool {
+	n := uint64(len(sb.shards))
+	start := rand.Uint64N(n)
+	for i := uint64(0); i < n; i++ {
+		if sb.shards[(start+i)%n].Enqueue(id, price, qty) {
+			return true
+		}
+	}
+	return false
+}
+
+// Dequeue takes an item from some non-empty shard, returning false only if
+// every shard is empty.
+func (sb *ShardedBuffer) Dequeue(id *uint64, price *float64, qty *uint32) bool {
+	n := uint64(len(sb.shards))
+	start := rand.Uint64N(n)
+	for i := uint64(0); i < n; i++ {
+		if sb.shards[(start+i)%n].Dequeue(id, price, qty) {
+			return true
+		}
+	}
+	return false
+}
+
+// Broadcast offers a copy of the item to every shard and returns how many
+// accepted it; anything short of the shard count means some shards were
+// full. The copies are independent, so consumers may see them in any order
+// and dequeue some before the others are written.
+func (sb *ShardedBuffer) Broadcast(id uint64, price float64, qty uint32) int {
+	accepted := 0
+	for _, shard := range sb.shards {
+		if shard.Enqueue(id, price, qty) {
+			accepted++
+		}
+	}
+	return accepted
+}
@@ -0,0 +1,53 @@
+package main
+
+import "testing"
+
+func TestRoundRobinEnqueueSkipsFullBuffer(t *testing.T) {
+	full, empty := Newbuffer(2), Newbuffer(2)
+	full.Enqueue(1, 0, 0)
+	full.Enqueue(2, 0, 0)
+	bufs := []*RingBuffer{full, empty}
+
+	for i := range uint64(2) {
+		if !RoundRobinEnqueue(bufs, Order{ID: 10 + i}) {
+			t.Fatalf("RoundRobinEnqueue %d failed with room in the second buffer", i)
+		}
+	}
+	if full.Len() != 2 || empty.Len() != 2 {
+		t.Fatalf("Len() = %d and %d, want 2 and 2", full.Len(), empty.Len())
+	}
+	if RoundRobinEnqueue(bufs, Order{ID: 12}) {
+		t.Fatal("RoundRobinEnqueue succeeded with every buffer full")
+	}
+	if RoundRobinEnqueue(nil, Order{}) {
+		t.Fatal("RoundRobinEnqueue succeeded with no buffers")
+	}
+}
+
+// TestShardedBroadcastPartial fills one shard and checks that Broadcast
+// reports the partial success and that every other shard got its copy.
+func TestShardedBroadcastPartial(t *testing.T) {
+	sb := NewShardedBuffer(4, 2)
+	if n := sb.Broadcast(1, 1.5, 10); n != 4 {
+		t.Fatalf("Broadcast into empty shards = %d, want 4", n)
+	}
+	sb.shards[2].Enqueue(99, 0, 0)
+	if n := sb.Broadcast(2, 2.5, 20); n != 3 {
+		t.Fatalf("Broadcast with one shard full = %d, want 3", n)
+	}
+	if n := sb.Broadcast(3, 0, 0); n != 0 {
+		t.Fatalf("Broadcast with every shard full = %d, want 0", n)
+	}
+
+	for i, shard := range sb.shards {
+		want := []Order{{1, 1.5, 10}, {2, 2.5, 20}}
+		if i == 2 {
+			want[1] = Order{ID: 99}
+		}
+		for _, w := range want {
+			if o, ok := shard.DequeueOrder(); !ok || o != w {
+				t.Fatalf("shard %d: DequeueOrder = %+v, %v, want %+v", i, o, ok, w)
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// BufferState is a diagnostic view of a buffer. HeadCycle is the cycle state
+// of the slot the next producer wants (free when it equals WriteIndex) and
+// TailCycle that of the slot the next consumer wants (published when it
+// equals ReadIndex+1), which is usually enough to tell a full buffer from a
+// producer that claimed a slot and never published it.
+type BufferState struct {
+	WriteIndex uint64
+	ReadIndex  uint64
+	Capacity   uint64
+	Pending    uint64
+	HeadCycle  uint64
+	TailCycle  uint64
+	Closed     bool
+}
+
+// Snapshot reads the indices and the head and tail cycle states with atomics,
+// one at a time, so under load the fields need not agree with each other.
+func (rb *RingBuffer) Snapshot() BufferState {
+	tail := atomic.LoadUint64(&rb.readIndex)
+	head := atomic.LoadUint64(&rb.writeIndex)
+	return BufferState{
+		WriteIndex: head,
+		ReadIndex:  tail,
+		Capacity:   rb.capacity,
+		Pending:    rb.Len(),
+		HeadCycle:  uint64(loadCycle(&rb.cycleState[head&rb.mask])),
+		TailCycle:  uint64(loadCycle(&rb.cycleState[tail&rb.mask])),
+		Closed:     rb.Closed(),
+	}
+}
+
+func (s BufferState) String() string {
+	return fmt.Sprintf("write=%d read=%d pending=%d/%d head-cycle=%d tail-cycle=%d closed=%t",
+		s.WriteIndex, s.ReadIndex, s.Pending, s.Capacity, s.HeadCycle, s.TailCycle, s.Closed)
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var (
+	soakDuration = flag.Duration("soak.duration", 2*time.Second, "how long TestSoak runs")
+	soakSeed     = flag.Uint64("soak.seed", 0, "seed for TestSoak; 0 picks one from the clock")
+)
+
+const (
+	soakProducers = 4
+	soakConsumers = 4
+	soakMaxBatch  = 32
+	soakLogSize   = 32
+
+	producerShift = 48
+	seqMask       = 1<<producerShift - 1
+)
+
+type soakOp struct {
+	op  string
+	seq uint64
+	n   uint64
+}
+
+// opLog keeps the last soakLogSize operations of one goroutine.
+type opLog struct {
+	ops  [soakLogSize]soakOp
+	next int
+}
+
+func (l *opLog) add(op string, seq, n uint64) {
+	l.ops[l.next%soakLogSize] = soakOp{op, seq, n}
+	l.next++
+}
+
+func (l *opLog) dump(b *strings.Builder, name string) {
+	fmt.Fprintf(b, "  %s:\n", name)
+	for i := max(0, l.next-soakLogSize); i < l.next; i++ {
+		o := l.ops[i%soakLogSize]
+		fmt.Fprintf(b, "    %-20s seq=%d n=%d\n", o.op, o.seq, o.n)
+	}
+}
+
+// tally is an order-independent fingerprint of a multiset of IDs: equal
+// tallies mean, with overwhelming probability, the same items each seen once.
+type tally struct {
+	count uint64
+	sum   uint64
+}
+
+func (t *tally) add(id uint64) {
+	t.count++
+	t.sum += mix(id)
+}
+
+func mix(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	return x ^ x>>33
+}
+
+// TestSoak drives randomized single and batch operations from several
+// producers and consumers for -soak.duration, then drains the buffer and
+// checks that every produced item was consumed exactly once and that each
+// consumer saw every producer's items in the order they were produced. All
+// random choices derive from -soak.seed, which is logged so a failing run
+// can be repeated:
+//
+//	go test -run Soak -timeout 10m -soak.duration=5m -soak.seed=N
+func TestSoak(t *testing.T) {
+	if testing.Short() {
+		t.Skip("soak test skipped in short mode")
+	}
+	duration := *soakDuration
+	seed := *soakSeed
+	if seed == 0 {
+		seed = uint64(time.Now().UnixNano())
+	}
+	rng := rand.New(rand.NewPCG(seed, 0))
+	capacity := uint64(1) << (3 + rng.IntN(8))
+	t.Logf("seed=%d capacity=%d duration=%v", seed, capacity, duration)
+
+	rb := Newbuffer(capacity)
+	var stop, producersDone, failed atomic.Bool
+	var failure atomic.Value
+	fail := func(msg string) {
+		if failed.CompareAndSwap(false, true) {
+			failure.Store(msg)
+		}
+		stop.Store(true)
+	}
+
+	produced := make([]tally, soakProducers)
+	consumed := make([][soakProducers]tally, soakConsumers)
+	logs := make([]opLog, soakProducers+soakConsumers)
+
+	var wg sync.WaitGroup
+	wg.Add(soakProducers)
+	for p := 0; p < soakProducers; p++ {
+		go func() {
+			defer wg.Done()
+			r := rand.New(rand.NewPCG(seed, uint64(1+p)))
+			log := &logs[p]
+			ids := make([]uint64, soakMaxBatch)
+			prices := make([]float64, soakMaxBatch)
+			qtys := make([]uint32, soakMaxBatch)
+			orders := make([]Order, soakMaxBatch)
+
+			next := uint64(0)
+			for !stop.Load() {
+				n := uint64(1 + r.IntN(min(soakMaxBatch, int(capacity))))
+				for i := uint64(0); i < n; i++ {
+					ids[i] = uint64(p)<<producerShift | (next + i)
+				}
+
+				var wrote uint64
+				switch r.IntN(4) {
+				case 0:
+					if rb.Enqueue(ids[0], prices[0], qtys[0]) {
+						wrote = 1
+					}
+					log.add("Enqueue", next, wrote)
+				case 1:
+					wrote = rb.EnqueueBatch(ids[:n], prices[:n], qtys[:n])
+					log.add("EnqueueBatch", next, wrote)
+				case 2:
+					wrote = rb.EnqueueRepeat(Order{ID: ids[0]}, 1)
+					log.add("EnqueueRepeat", next, wrote)
+				case 3:
+					for i := uint64(0); i < n; i++ {
+						orders[i] = Order{ID: ids[i]}
+					}
+					wrote = rb.EnqueueOrders(orders[:n])
+					log.add("EnqueueOrders", next, wrote)
+				}
+
+				for i := uint64(0); i < wrote; i++ {
+					produced[p].add(ids[i])
+				}
+				next += wrote
+				if wrote == 0 {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+
+	var consumerWg sync.WaitGroup
+	consumerWg.Add(soakConsumers)
+	for c := 0; c < soakConsumers; c++ {
+		go func() {
+			defer consumerWg.Done()
+			r := rand.New(rand.NewPCG(seed, uint64(1+soakProducers+c)))
+			log := &logs[soakProducers+c]
+			ids := make([]uint64, soakMaxBatch)
+			prices := make([]float64, soakMaxBatch)
+			qtys := make([]uint32, soakMaxBatch)
+
+			var lastSeq [soakProducers]int64
+			for i := range lastSeq {
+				lastSeq[i] = -1
+			}
+
+			for !failed.Load() {
+				n := uint64(1 + r.IntN(min(soakMaxBatch, int(capacity))))
+				var got uint64
+				switch r.IntN(4) {
+				case 0:
+					if rb.Dequeue(&ids[0], &prices[0], &qtys[0]) {
+						got = 1
+					}
+					log.add("Dequeue", 0, got)
+				case 1:
+					got = rb.DequeueBatch(ids[:n], prices[:n], qtys[:n])
+					log.add("DequeueBatch", 0, got)
+				case 2:
+					got = rb.DequeueBatchNoWait(ids[:n], prices[:n], qtys[:n])
+					log.add("DequeueBatchNoWait", 0, got)
+				case 3:
+					got = rb.DequeueBatchMin(1, int(n), ids, prices, qtys)
+					log.add("DequeueBatchMin", 0, got)
+				}
+
+				for i := uint64(0); i < got; i++ {
+					p := ids[i] >> producerShift
+					seq := int64(ids[i] & seqMask)
+					if p >= soakProducers {
+						fail(fmt.Sprintf("consumer %d: corrupt id %#x", c, ids[i]))
+						return
+					}
+					if seq <= lastSeq[p] {
+						fail(fmt.Sprintf("consumer %d: producer %d seq %d after %d", c, p, seq, lastSeq[p]))
+						return
+					}
+					lastSeq[p] = seq
+					consumed[c][p].add(ids[i])
+				}
+
+				if got == 0 {
+					if producersDone.Load() && rb.IsEmpty() {
+						return
+					}
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+
+	time.Sleep(duration)
+	stop.Store(true)
+	wg.Wait()
+	producersDone.Store(true)
+	consumerWg.Wait()
+
+	if !failed.Load() {
+		for p := 0; p < soakProducers; p++ {
+			var total tally
+			for c := range consumed {
+				total.count += consumed[c][p].count
+				total.sum += consumed[c][p].sum
+			}
+			if total != produced[p] {
+				fail(fmt.Sprintf("producer %d: produced %d items, consumed %d (or a different set)",
+					p, produced[p].count, total.count))
+				break
+			}
+		}
+	}
+
+	if failed.Load() {
+		var b strings.Builder
+		for i := range logs {
+			logs[i].dump(&b, soakRole(i))
+		}
+		t.Fatalf("%s\nreproduce with -soak.seed=%d\n%s", failure.Load(), seed, b.String())
+	}
+
+	var total uint64
+	for p := range produced {
+		total += produced[p].count
+	}
+	t.Logf("%d items", total)
+}
+
+func soakRole(i int) string {
+	if i < soakProducers {
+		return fmt.Sprintf("producer %d", i)
+	}
+	return fmt.Sprintf("consumer %d", i-soakProducers)
+}
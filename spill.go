@@ -0,0 +1,181 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// spillPoll is how often the reconciler retries a full primary ring, after a
+// few yields.
+const spillPoll = 100 * time.Microsecond
+
+// spillover holds the items Enqueue could not fit into the ring, oldest
+// first, and runs the goroutine that moves them back in. pending mirrors the
+// queue length so the fast path can skip the lock; it only drops once an item
+// is in the ring, so a producer that reads 0 has none of its own items left
+// in the queue.
+type spillover struct {
+	limit   int
+	pending atomic.Int64
+
+	mu    sync.Mutex
+	items []Order
+	head  int
+
+	wake    chan struct{}
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// WithSpillover makes Enqueue append to an overflow queue when the ring is
+// full instead of failing, and starts a goroutine that moves the queued items
+// back into the ring as consumers free slots. Once anything is queued, every
+// Enqueue queues behind it, so each producer's items still come out in the
+// order it enqueued them. Consumers only see the ring: an item waits in the
+// queue until the reconciler has moved it over, and Len does not count it;
+// Spilled does. Only Enqueue and the calls built on it spill, and
+// PolicyOverwrite never finds the ring full, so it never spills either.
+//
+// The queue is unbounded: producers that outrun the consumers for long just
+// move the backlog from a fixed ring onto the heap, and a stalled consumer
+// lets it grow until the process runs out of memory. WithSpilloverLimit caps
+// it.
+//
+// The reconciler lives until the buffer is closed and the queue has drained
+// into the ring, so a buffer with spillover must be closed to be collected.
+// As with Close generally, producers must be done before it is called.
+func WithSpillover() Option {
+	return func(c *bufferConfig) {
+		if c.spillLimit == 0 {
+			c.spillLimit = -1
+		}
+	}
+}
+
+// WithSpilloverLimit is WithSpillover with a queue of at most n items; once
+// it holds n, Enqueue fails as it would on a full ring without spillover.
+func WithSpilloverLimit(n int) Option {
+	return func(c *bufferConfig) {
+		if n < 1 {
+			panic("ring: spillover limit must be positive")
+		}
+		c.spillLimit = n
+	}
+}
+
+// newSpillover returns nil for a limit of 0, which means no spillover, and an
+// unbounded queue for a negative one.
+func newSpillover(limit int) *spillover {
+	if limit == 0 {
+		return nil
+	}
+	return &spillover{
+		limit:   limit,
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// enqueue stores an item in the ring while nothing is queued, and queues it
+// otherwise, so it never overtakes an older item of the same producer.
+func (s *spillover) enqueue(rb *RingBuffer, id uint64, price float64, qty uint32) bool {
+	if s.pending.Load() == 0 && rb.enqueue(id, price, qty) {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.limit > 0 && len(s.items)-s.head >= s.limit {
+		return false
+	}
+	s.items = append(s.items, Order{ID: id, Price: price, Qty: qty})
+	s.pending.Add(1)
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// run moves queued items into the ring until the buffer is closed with the
+// queue empty, or until cancel.
+func (s *spillover) run(rb *RingBuffer) {
+	defer close(s.stopped)
+	closing := rb.done.closing
+	wait := Backoff(0, passiveSpin, spillPoll)
+	for attempt := 0; ; {
+		if s.refill(rb) {
+			attempt = 0
+			select {
+			case <-s.wake:
+				continue
+			case <-closing:
+				if s.empty() {
+					return
+				}
+				continue
+			case <-s.stop:
+				return
+			}
+		}
+
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		wait.Wait(attempt)
+		attempt++
+	}
+}
+
+// refill moves as many queued items into the ring as fit, oldest first, and
+// reports whether the queue is now empty.
+func (s *spillover) refill(rb *RingBuffer) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.head < len(s.items) {
+		o := s.items[s.head]
+		if !rb.enqueue(o.ID, o.Price, o.Qty) {
+			break
+		}
+		s.head++
+		s.pending.Add(-1)
+	}
+	if s.head == len(s.items) {
+		s.items, s.head = s.items[:0], 0
+		return true
+	}
+	if s.head > len(s.items)/2 {
+		n := copy(s.items, s.items[s.head:])
+		s.items, s.head = s.items[:n], 0
+	}
+	return false
+}
+
+// empty reports whether nothing is queued. It is nil-safe, so buffers without
+// spillover need no check of their own.
+func (s *spillover) empty() bool {
+	return s == nil || s.pending.Load() == 0
+}
+
+// cancel stops the reconciler and waits for it to return, dropping whatever
+// is still queued. Reset calls it before it replaces the spillover.
+func (s *spillover) cancel() {
+	if s == nil {
+		return
+	}
+	close(s.stop)
+	<-s.stopped
+}
+
+// Spilled returns how many items wait in the spillover queue for room in the
+// ring. It is 0 without WithSpillover.
+func (rb *RingBuffer) Spilled() int {
+	if rb.spill == nil {
+		return 0
+	}
+	return int(rb.spill.pending.Load())
+}
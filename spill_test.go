@@ -0,0 +1,122 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestSpilloverBurst enqueues ten times the capacity with no consumer running,
+// then drains: every item must arrive, in the order it was enqueued.
+func TestSpilloverBurst(t *testing.T) {
+	const capacity, total = 16, 160
+	rb := NewbufferOpts(capacity, WithSpillover())
+	for i := range uint64(total) {
+		if !rb.Enqueue(i, float64(i), uint32(i)) {
+			t.Fatalf("Enqueue(%d) failed with spillover on", i)
+		}
+	}
+	if got := rb.Spilled(); got != total-capacity {
+		t.Fatalf("Spilled() = %d after the burst, want %d", got, total-capacity)
+	}
+	rb.Close()
+
+	for i := range uint64(total) {
+		o, ok := rb.DequeueBlocking()
+		if !ok || o != (Order{ID: i, Price: float64(i), Qty: uint32(i)}) {
+			t.Fatalf("item %d = %+v, %v", i, o, ok)
+		}
+	}
+	if _, ok := rb.DequeueBlocking(); ok {
+		t.Fatal("dequeued an item past the end")
+	}
+	select {
+	case <-rb.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Done not closed after draining ring and spillover")
+	}
+}
+
+// TestSpilloverConcurrentConsumer keeps a consumer running while the producer
+// overruns the ring, so the reconciler refills it while items flow out.
+func TestSpilloverConcurrentConsumer(t *testing.T) {
+	const total = 20_000
+	rb := NewbufferOpts(8, WithSpillover())
+	got := make(chan []uint64)
+	go func() {
+		var ids []uint64
+		for {
+			o, ok := rb.DequeueBlocking()
+			if !ok {
+				got <- ids
+				return
+			}
+			ids = append(ids, o.ID)
+		}
+	}()
+
+	for i := range uint64(total) {
+		if !rb.Enqueue(i, 0, 0) {
+			t.Fatalf("Enqueue(%d) failed with spillover on", i)
+		}
+	}
+	rb.Close()
+
+	ids := <-got
+	if len(ids) != total {
+		t.Fatalf("consumer got %d items, want %d", len(ids), total)
+	}
+	for i, id := range ids {
+		if id != uint64(i) {
+			t.Fatalf("item %d has ID %d", i, id)
+		}
+	}
+}
+
+func TestSpilloverLimit(t *testing.T) {
+	rb := NewbufferOpts(4, WithSpilloverLimit(2))
+	defer rb.Close()
+	for i := range uint64(6) {
+		if !rb.Enqueue(i, 0, 0) {
+			t.Fatalf("Enqueue(%d) failed below the limit", i)
+		}
+	}
+	if rb.Enqueue(6, 0, 0) {
+		t.Fatal("Enqueue succeeded with ring and spillover full")
+	}
+
+	var o Order
+	for i := range uint64(6) {
+		deadline := time.Now().Add(5 * time.Second)
+		for !rb.DequeueInto(&o) {
+			if time.Now().After(deadline) {
+				t.Fatalf("item %d never reached the ring", i)
+			}
+			time.Sleep(time.Millisecond)
+		}
+		if o.ID != i {
+			t.Fatalf("item %d has ID %d", i, o.ID)
+		}
+	}
+}
+
+func TestSpilloverReset(t *testing.T) {
+	rb := NewbufferOpts(2, WithSpillover())
+	for i := range uint64(5) {
+		rb.Enqueue(i, 0, 0)
+	}
+	rb.Reset()
+	if rb.Spilled() != 0 || rb.Len() != 0 {
+		t.Fatalf("after Reset: Spilled() = %d, Len() = %d", rb.Spilled(), rb.Len())
+	}
+
+	for i := range uint64(3) {
+		rb.Enqueue(10+i, 0, 0)
+	}
+	rb.Close()
+	for i := range uint64(3) {
+		o, ok := rb.DequeueBlocking()
+		if !ok || o.ID != 10+i {
+			t.Fatalf("item %d after Reset = %d, %v", i, o.ID, ok)
+		}
+	}
+}
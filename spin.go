@@ -0,0 +1,46 @@
+package main
+
+import (
+	"runtime"
+	"time"
+)
+
+const (
+	activeSpin       = 4
+	activeSpinCycles = 30
+
+	passiveSpin = 16
+	spinSleep   = 20 * time.Microsecond
+)
+
+// DefaultSpinWait is what a consumer does while it waits for a producer that
+// has claimed a slot but not yet published it, unless WithSpinWait says
+// otherwise. The first activeSpin iterations only pause the core, which is far
+// cheaper than a scheduler round trip when the producer is a few nanoseconds
+// from finishing; the next passiveSpin hand the processor to the scheduler so
+// a descheduled producer can run; after that the wait sleeps, so a producer
+// that stalls for long doesn't cost a core.
+var DefaultSpinWait = Backoff(activeSpin, passiveSpin, spinSleep)
+
+type backoff struct {
+	spins, yields int
+	sleep         time.Duration
+}
+
+// Backoff pauses the core for the first spins attempts, yields to the
+// scheduler for the next yields attempts and sleeps for sleep on every
+// attempt after that. A negative yields never sleeps.
+func Backoff(spins, yields int, sleep time.Duration) WaitStrategy {
+	return backoff{spins: spins, yields: yields, sleep: sleep}
+}
+
+func (b backoff) Wait(attempt int) {
+	switch {
+	case attempt < b.spins:
+		procyield(activeSpinCycles)
+	case b.yields < 0 || attempt < b.spins+b.yields:
+		runtime.Gosched()
+	default:
+		time.Sleep(b.sleep)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build amd64 || arm64
+
+package main
+
+// procyield executes the CPU's spin-wait hint (PAUSE on x86, YIELD on ARM)
+// cycles times. cycles must be non-zero.
+func procyield(cycles uint32)
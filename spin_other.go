@@ -0,0 +1,11 @@
+//go:build !amd64 && !arm64
+
+package main
+
+// procyield is the portable fallback for architectures without a spin-wait
+// hint: a short busy loop. The gc compiler keeps empty loops, and the loop
+// touches no shared memory, so concurrent spinners don't race on a counter.
+func procyield(cycles uint32) {
+	for i := uint32(0); i < cycles; i++ {
+	}
+}
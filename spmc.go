@@ -0,0 +1,85 @@
+package main
+
+import "sync/atomic"
+
+// SPMCBuffer is a fan-out ring: exactly one producer goroutine, any number of
+// consumer goroutines. Consumers claim slots with a CAS on readIndex and
+// release them through the per-slot cycle state as in RingBuffer. The
+// producer owns writeIndex outright, so it advances it with a plain store and
+// never CASes; it still checks the slot's cycle state, because consumers
+// release slots out of order. Enqueue from two goroutines at once lets both
+// write the same slot and loses an item; see cmd/racedemo.
+type SPMCBuffer struct {
+	capacity uint64
+	mask     uint64
+	_        [CacheLineSize]byte
+
+	writeIndex uint64
+	_          [CacheLineSize - 8]byte
+
+	readIndex uint64
+	_         [CacheLineSize - 8]byte
+
+	cycleState []cycle
+	ids        []uint64
+	prices     []float64
+	qtys       []uint32
+}
+
+// NewSPMCBuffer returns a buffer of the given capacity, which must be a power
+// of two.
+func NewSPMCBuffer(capacity uint64) *SPMCBuffer {
+	checkCapacity("NewSPMCBuffer", capacity)
+
+	buffer := &SPMCBuffer{
+		capacity:   capacity,
+		mask:       capacity - 1,
+		cycleState: make([]cycle, capacity),
+		ids:        make([]uint64, capacity),
+		prices:     make([]float64, capacity),
+		qtys:       make([]uint32, capacity),
+	}
+	initCycleState(buffer.cycleState)
+	return buffer
+}
+
+// Enqueue must only be called from the single producer goroutine.
+func (rb *SPMCBuffer) Enqueue(id uint64, price float64, qty uint32) bool {
+	head := rb.writeIndex
+	offset := head & rb.mask
+	if cycleDiff(loadCycle(&rb.cycleState[offset]), head) != 0 {
+		return false
+	}
+
+	rb.ids[offset] = id
+	rb.prices[offset] = price
+	rb.qtys[offset] = qty
+	storeCycle(&rb.cycleState[offset], head+1)
+	rb.writeIndex = head + 1
+	return true
+}
+
+func (rb *SPMCBuffer) Dequeue(id *uint64, price *float64, qty *uint32) bool {
+	var tail uint64
+	var offset uint64
+
+	for {
+		tail = atomic.LoadUint64(&rb.readIndex)
+		offset = tail & rb.mask
+		diff := cycleDiff(loadCycle(&rb.cycleState[offset]), tail+1)
+
+		if diff == 0 {
+			if atomic.CompareAndSwapUint64(&rb.readIndex, tail, tail+1) {
+				break
+			}
+		} else if diff < 0 {
+			return false
+		}
+	}
+
+	*id = rb.ids[offset]
+	*price = rb.prices[offset]
+	*qty = rb.qtys[offset]
+	storeCycle(&rb.cycleState[offset], tail+rb.capacity)
+	return true
+}
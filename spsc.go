@@ -0,0 +1,77 @@
+package main
+
+import "sync/atomic"
+
+// SPSCBuffer is a ring for exactly one producer goroutine and one consumer
+// goroutine. With a single writer per index there is nothing to CAS and no
+// per-slot cycle state: the producer publishes by storing writeIndex and the
+// consumer releases by storing readIndex. Each side also keeps a cached copy
+// of the other side's index and only reloads it when the cache says the
+// buffer is full (or empty), so in steady state neither touches the other's
+// cache line. Calling Enqueue or Dequeue from more than one goroutine at a
+// time corrupts the buffer.
+type SPSCBuffer struct {
+	capacity uint64
+	mask     uint64
+	_        [CacheLineSize]byte
+
+	writeIndex uint64
+	cachedRead uint64
+	_          [CacheLineSize - 16]byte
+
+	readIndex   uint64
+	cachedWrite uint64
+	_           [CacheLineSize - 16]byte
+
+	ids    []uint64
+	prices []float64
+	qtys   []uint32
+}
+
+// NewSPSCBuffer returns a buffer of the given capacity, which must be a power
+// of two.
+func NewSPSCBuffer(capacity uint64) *SPSCBuffer {
+	checkCapacity("NewSPSCBuffer", capacity)
+
+	return &SPSCBuffer{
+		capacity: capacity,
+		mask:     capacity - 1,
+		ids:      make([]uint64, capacity),
+		prices:   make([]float64, capacity),
+		qtys:     make([]uint32, capacity),
+	}
+}
+
+func (rb *SPSCBuffer) Enqueue(id uint64, price float64, qty uint32) bool {
+	head := rb.writeIndex
+	if head-rb.cachedRead == rb.capacity {
+		rb.cachedRead = atomic.LoadUint64(&rb.readIndex)
+		if head-rb.cachedRead == rb.capacity {
+			return false
+		}
+	}
+
+	offset := head & rb.mask
+	rb.ids[offset] = id
+	rb.prices[offset] = price
+	rb.qtys[offset] = qty
+	atomic.StoreUint64(&rb.writeIndex, head+1)
+	return true
+}
+
+func (rb *SPSCBuffer) Dequeue(id *uint64, price *float64, qty *uint32) bool {
+	tail := rb.readIndex
+	if tail == rb.cachedWrite {
+		rb.cachedWrite = atomic.LoadUint64(&rb.writeIndex)
+		if tail == rb.cachedWrite {
+			return false
+		}
+	}
+
+	offset := tail & rb.mask
+	*id = rb.ids[offset]
+	*price = rb.prices[offset]
+	*qty = rb.qtys[offset]
+	atomic.StoreUint64(&rb.readIndex, tail+1)
+	return true
+}
@@ -0,0 +1,105 @@
+package main
+
+import "sync/atomic"
+
+// Consumer is one stage of a pipeline over a RingBuffer, in the style of the
+// LMAX Disruptor's sequence barriers. Each stage owns a cursor, the sequence
+// of the next slot it will handle. The first stage follows the producers and
+// a dependent stage follows its upstream stage's cursor, so items pass through
+// every stage in order without being copied between buffers. Only the last
+// stage releases slots back to producers.
+//
+// Stages form a single chain: build it with NewConsumer and
+// NewDependentConsumer before any stage runs, drive each stage from exactly
+// one goroutine, and don't mix stages with Dequeue or the other consuming
+// calls on the same buffer.
+type Consumer struct {
+	cursor uint64
+	_      [CacheLineSize - 8]byte
+
+	rb         *RingBuffer
+	upstream   *Consumer
+	downstream *Consumer
+}
+
+// NewConsumer returns the first stage of a pipeline, which sees slots as
+// soon as producers publish them.
+func (rb *RingBuffer) NewConsumer() *Consumer {
+	return &Consumer{cursor: atomic.LoadUint64(&rb.readIndex), rb: rb}
+}
+
+// NewDependentConsumer returns a stage that only sees slots upstream has
+// finished with. It panics if upstream belongs to another buffer or already
+// has a dependent stage.
+func (rb *RingBuffer) NewDependentConsumer(upstream *Consumer) *Consumer {
+	if upstream.rb != rb {
+		panic("ring: upstream consumer belongs to another buffer")
+	}
+	if upstream.downstream != nil {
+		panic("ring: upstream consumer already has a dependent stage")
+	}
+
+	c := &Consumer{cursor: upstream.cursor, rb: rb, upstream: upstream}
+	upstream.downstream = c
+	return c
+}
+
+// Dequeue copies the next slot available to this stage into o and moves past
+// it, or returns false if the stage has caught up.
+func (c *Consumer) Dequeue(o *Order) bool {
+	seq, ok := c.next()
+	if !ok {
+		return false
+	}
+
+	*o = c.rb.GetAt(seq)
+	c.advance(seq)
+	return true
+}
+
+// Update calls fn with the next slot available to this stage and writes back
+// whatever fn leaves in it, so later stages see the change. It returns false
+// without calling fn if the stage has caught up.
+func (c *Consumer) Update(fn func(*Order)) bool {
+	seq, ok := c.next()
+	if !ok {
+		return false
+	}
+
+	o := c.rb.GetAt(seq)
+	fn(&o)
+	offset := seq & c.rb.mask
+	c.rb.ids[offset] = o.ID
+	c.rb.prices[offset] = o.Price
+	c.rb.qtys[offset] = o.Qty
+	c.advance(seq)
+	return true
+}
+
+// Cursor returns the sequence of the next slot this stage will handle.
+func (c *Consumer) Cursor() uint64 {
+	return atomic.LoadUint64(&c.cursor)
+}
+
+// next returns the sequence this stage handles next if its barrier allows it:
+// the slot must be published for the first stage, and behind the upstream
+// cursor for the others.
+func (c *Consumer) next() (uint64, bool) {
+	seq := c.cursor
+	if c.upstream != nil {
+		return seq, seq < atomic.LoadUint64(&c.upstream.cursor)
+	}
+	return seq, cycleDiff(loadCycle(&c.rb.cycleState[seq&c.rb.mask]), seq+1) == 0
+}
+
+// advance publishes that this stage is done with seq. The atomic store of
+// the cursor orders the stage's writes to the slot before a dependent stage
+// reads it; the last stage hands the slot back to producers instead.
+func (c *Consumer) advance(seq uint64) {
+	if c.downstream == nil {
+		c.rb.ReleaseRead(seq)
+		atomic.StoreUint64(&c.rb.readIndex, seq+1)
+		c.rb.stats.addDequeued(1)
+	}
+	atomic.StoreUint64(&c.cursor, seq+1)
+}
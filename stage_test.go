@@ -0,0 +1,101 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+)
+
+// TestStageBarrier checks, step by step, that a dependent stage sees nothing
+// until its upstream stage has handled a slot, and that producers get a slot
+// back only once the last stage is done with it.
+func TestStageBarrier(t *testing.T) {
+	rb := Newbuffer(2)
+	a := rb.NewConsumer()
+	b := rb.NewDependentConsumer(a)
+	var o Order
+
+	rb.Enqueue(1, 0, 0)
+	rb.Enqueue(2, 0, 0)
+	if b.Dequeue(&o) {
+		t.Fatal("dependent stage ran ahead of its upstream")
+	}
+	if !a.Update(func(o *Order) { o.Qty = 10 }) {
+		t.Fatal("first stage found nothing to handle")
+	}
+	if rb.Enqueue(3, 0, 0) {
+		t.Fatal("producer reclaimed a slot the last stage has not handled")
+	}
+	if !b.Dequeue(&o) || o.ID != 1 || o.Qty != 10 {
+		t.Fatalf("dependent stage got %+v, want item 1 with the first stage's Qty 10", o)
+	}
+	if b.Dequeue(&o) {
+		t.Fatal("dependent stage passed its upstream's cursor")
+	}
+	if !rb.Enqueue(3, 0, 0) {
+		t.Fatal("slot not released after the last stage handled it")
+	}
+	if a.Cursor() != 1 || b.Cursor() != 1 {
+		t.Fatalf("cursors %d, %d, want 1, 1", a.Cursor(), b.Cursor())
+	}
+}
+
+// TestStagePipeline runs a producer, a transforming stage and a final stage
+// on their own goroutines over many laps of a small ring. The final stage
+// must see every item in order, each with the first stage's change applied.
+func TestStagePipeline(t *testing.T) {
+	const total = 50_000
+	rb := Newbuffer(16)
+	a := rb.NewConsumer()
+	b := rb.NewDependentConsumer(a)
+
+	go func() {
+		for i := range uint64(total) {
+			for !rb.Enqueue(i, float64(i), 0) {
+				runtime.Gosched()
+			}
+		}
+	}()
+	go func() {
+		for handled := 0; handled < total; {
+			if a.Update(func(o *Order) { o.Qty = uint32(o.ID) * 3 }) {
+				handled++
+			} else {
+				runtime.Gosched()
+			}
+		}
+	}()
+
+	var o Order
+	for want := uint64(0); want < total; {
+		if !b.Dequeue(&o) {
+			runtime.Gosched()
+			continue
+		}
+		if o.ID != want || o.Price != float64(want) || o.Qty != uint32(want)*3 {
+			t.Fatalf("final stage got %+v, want item %d with Qty %d", o, want, want*3)
+		}
+		want++
+	}
+	if !rb.IsEmpty() {
+		t.Fatalf("Len() = %d after the pipeline drained", rb.Len())
+	}
+}
+
+func TestNewDependentConsumerPanics(t *testing.T) {
+	rb := Newbuffer(8)
+	a := rb.NewConsumer()
+	rb.NewDependentConsumer(a)
+	for name, fn := range map[string]func(){
+		"second dependent": func() { rb.NewDependentConsumer(a) },
+		"other buffer":     func() { Newbuffer(8).NewDependentConsumer(rb.NewConsumer()) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: NewDependentConsumer did not panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
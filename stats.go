@@ -0,0 +1,185 @@
+package main
+
+import "sync/atomic"
+
+// Stats is a snapshot of a buffer's operation counters. Enqueued and Dequeued
+// count items; FullAttempts and EmptyAttempts count calls that returned
+// without moving anything because the buffer was full or empty, a sign of
+// backpressure; EnqueueRetries and DequeueRetries count every retry of a
+// claim on writeIndex and readIndex. WriteCASFailures and ReadCASFailures
+// count the subset that were lost CAS races, the rest being retries from an
+// index that was already stale when it was read. Whichever of the two
+// dominates says where the contention is: many write failures call for
+// sharding producers, for example with ShardedBuffer or Batchers,
+// many read failures for batching or sharding consumers.
+type Stats struct {
+	Enqueued         uint64
+	Dequeued         uint64
+	FullAttempts     uint64
+	EmptyAttempts    uint64
+	EnqueueRetries   uint64
+	DequeueRetries   uint64
+	WriteCASFailures uint64
+	ReadCASFailures  uint64
+}
+
+// bufferStats holds the live counters, with the producer and consumer sides
+// on separate cache lines. A buffer built without metrics has a nil
+// *bufferStats, and every method is a no-op on nil, so the hot paths pay one
+// predictable branch.
+type bufferStats struct {
+	enqueued         uint64
+	full             uint64
+	enqueueRetries   uint64
+	highWater        uint64
+	writeCASFailures uint64
+	_                [CacheLineSize - 40]byte
+
+	dequeued        uint64
+	empty           uint64
+	dequeueRetries  uint64
+	readCASFailures uint64
+	_               [CacheLineSize - 32]byte
+
+	contention *contentionWarning
+}
+
+// contentionCheck is how many lost writeIndex races pass between checks of
+// the WithContentionWarning ratio.
+const contentionCheck = 1024
+
+type contentionWarning struct {
+	ratio float64
+	warn  func(Stats)
+}
+
+// NewbufferWithMetrics is Newbuffer with the Stats counters enabled.
+func NewbufferWithMetrics(capacity uint64) *RingBuffer {
+	return NewbufferOpts(capacity, WithMetrics(true))
+}
+
+// Stats returns the counters so far, or zeros if the buffer was built without
+// metrics. The fields are read one at a time, so under load they are not a
+// consistent cut.
+func (rb *RingBuffer) Stats() Stats {
+	return rb.stats.snapshot()
+}
+
+func (s *bufferStats) snapshot() Stats {
+	if s == nil {
+		return Stats{}
+	}
+	return Stats{
+		Enqueued:         atomic.LoadUint64(&s.enqueued),
+		Dequeued:         atomic.LoadUint64(&s.dequeued),
+		FullAttempts:     atomic.LoadUint64(&s.full),
+		EmptyAttempts:    atomic.LoadUint64(&s.empty),
+		EnqueueRetries:   atomic.LoadUint64(&s.enqueueRetries),
+		DequeueRetries:   atomic.LoadUint64(&s.dequeueRetries),
+		WriteCASFailures: atomic.LoadUint64(&s.writeCASFailures),
+		ReadCASFailures:  atomic.LoadUint64(&s.readCASFailures),
+	}
+}
+
+// HighWaterMark returns the most items the buffer has held at once since it
+// was built or since ResetHighWaterMark, or 0 if it was built without metrics.
+// A buffer that keeps reaching its capacity wants resizing or sharding.
+func (rb *RingBuffer) HighWaterMark() uint64 {
+	if rb.stats == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&rb.stats.highWater)
+}
+
+// ResetHighWaterMark starts tracking the high-water mark afresh, for example
+// at the start of each reporting interval.
+func (rb *RingBuffer) ResetHighWaterMark() {
+	if rb.stats != nil {
+		atomic.StoreUint64(&rb.stats.highWater, 0)
+	}
+}
+
+// countEnqueued records n enqueued items and raises the high-water mark to
+// the current fill level. It reads both indices, so it is done only when
+// metrics are on. The fill level is Len, so claimed but unpublished slots
+// count as held, and a readIndex that DequeueBatchFair has pushed past
+// writeIndex counts as empty rather than wrapping around.
+func (rb *RingBuffer) countEnqueued(n uint64) {
+	s := rb.stats
+	if s == nil {
+		return
+	}
+	s.addEnqueued(n)
+
+	fill := rb.Len()
+	for {
+		hw := atomic.LoadUint64(&s.highWater)
+		if fill <= hw || atomic.CompareAndSwapUint64(&s.highWater, hw, fill) {
+			return
+		}
+	}
+}
+
+func (s *bufferStats) addEnqueued(n uint64) {
+	if s != nil {
+		atomic.AddUint64(&s.enqueued, n)
+	}
+}
+
+func (s *bufferStats) addDequeued(n uint64) {
+	if s != nil {
+		atomic.AddUint64(&s.dequeued, n)
+	}
+}
+
+func (s *bufferStats) addFull() {
+	if s != nil {
+		atomic.AddUint64(&s.full, 1)
+	}
+}
+
+func (s *bufferStats) addEmpty() {
+	if s != nil {
+		atomic.AddUint64(&s.empty, 1)
+	}
+}
+
+func (s *bufferStats) addEnqueueRetry() {
+	if s != nil {
+		atomic.AddUint64(&s.enqueueRetries, 1)
+	}
+}
+
+func (s *bufferStats) addDequeueRetry() {
+	if s != nil {
+		atomic.AddUint64(&s.dequeueRetries, 1)
+	}
+}
+
+// addWriteCASFailure and addReadCASFailure record a lost CAS race on
+// writeIndex or readIndex, which is also a retry.
+func (s *bufferStats) addWriteCASFailure() {
+	if s != nil {
+		atomic.AddUint64(&s.enqueueRetries, 1)
+		n := atomic.AddUint64(&s.writeCASFailures, 1)
+		if s.contention != nil && n%contentionCheck == 0 {
+			s.checkContention(n)
+		}
+	}
+}
+
+// checkContention calls the WithContentionWarning callback if n lost races
+// are more than its ratio per item enqueued so far.
+func (s *bufferStats) checkContention(n uint64) {
+	enqueued := max(atomic.LoadUint64(&s.enqueued), 1)
+	if float64(n)/float64(enqueued) > s.contention.ratio {
+		s.contention.warn(s.snapshot())
+	}
+}
+
+func (s *bufferStats) addReadCASFailure() {
+	if s != nil {
+		atomic.AddUint64(&s.dequeueRetries, 1)
+		atomic.AddUint64(&s.readCASFailures, 1)
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHighWaterMark(t *testing.T) {
+	rb := NewbufferOpts(8, WithMetrics(true))
+	for i := range uint64(5) {
+		rb.Enqueue(i, 0, 0)
+	}
+	var o Order
+	for range 4 {
+		rb.DequeueInto(&o)
+	}
+	rb.Enqueue(5, 0, 0)
+	if hw := rb.HighWaterMark(); hw != 5 {
+		t.Fatalf("HighWaterMark() = %d, want 5", hw)
+	}
+	rb.ResetHighWaterMark()
+	rb.Enqueue(6, 0, 0)
+	if hw := rb.HighWaterMark(); hw != 3 {
+		t.Fatalf("HighWaterMark() after reset = %d, want 3", hw)
+	}
+	if hw := Newbuffer(8).HighWaterMark(); hw != 0 {
+		t.Fatalf("HighWaterMark() without metrics = %d, want 0", hw)
+	}
+}
+
+// TestHighWaterMarkFairTicket enqueues while a DequeueBatchFair ticket has
+// pushed readIndex past writeIndex. The fill level must read as empty, not
+// as the wrapped-around difference of the indices.
+func TestHighWaterMarkFairTicket(t *testing.T) {
+	rb := NewbufferOpts(8, WithMetrics(true))
+	ids := make([]uint64, 4)
+	var n atomic.Uint64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		n.Store(rb.DequeueBatchFair(ids, make([]float64, 4), make([]uint32, 4)))
+	}()
+	for atomic.LoadUint64(&rb.readIndex) != 4 {
+		runtime.Gosched()
+	}
+
+	rb.Enqueue(0, 0, 0)
+	if hw := rb.HighWaterMark(); hw > rb.Cap() {
+		t.Fatalf("HighWaterMark() = %d with readIndex ahead of writeIndex, want at most %d", hw, rb.Cap())
+	}
+	for i := range uint64(3) {
+		rb.Enqueue(i+1, 0, 0)
+	}
+	<-done
+	if n.Load() != 4 || ids[3] != 3 {
+		t.Fatalf("DequeueBatchFair = %d, %v, want 4 items", n.Load(), ids)
+	}
+	if hw := rb.HighWaterMark(); hw != 0 {
+		t.Fatalf("HighWaterMark() = %d, want 0: every item was claimed before it was enqueued", hw)
+	}
+}
+
+// TestCASFailuresProducerHeavy runs many single-item producers against one
+// batch consumer. Producers fight over writeIndex while the lone consumer
+// has readIndex to itself, so write failures must dominate. A CAS is only
+// lost to a goroutine running at the same time, so it needs two CPUs.
+func TestCASFailuresProducerHeavy(t *testing.T) {
+	if runtime.NumCPU() < 2 {
+		t.Skip("needs at least two CPUs to contend")
+	}
+	const (
+		producers = 16
+		perWorker = 20_000
+	)
+	rb := NewbufferOpts(1024, WithMetrics(true))
+	var wg sync.WaitGroup
+	wg.Add(producers)
+	for range producers {
+		go func() {
+			defer wg.Done()
+			for i := range uint64(perWorker) {
+				for !rb.Enqueue(i, 0, 0) {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+
+	ids := make([]uint64, 64)
+	prices := make([]float64, 64)
+	qtys := make([]uint32, 64)
+	for consumed := uint64(0); consumed < producers*perWorker; {
+		n := rb.DequeueBatchUpTo(ids, prices, qtys)
+		if n == 0 {
+			runtime.Gosched()
+		}
+		consumed += n
+	}
+	wg.Wait()
+
+	st := rb.Stats()
+	t.Logf("write CAS failures %d, read CAS failures %d", st.WriteCASFailures, st.ReadCASFailures)
+	if st.WriteCASFailures == 0 || st.WriteCASFailures <= st.ReadCASFailures {
+		t.Fatalf("WriteCASFailures = %d, ReadCASFailures = %d, want write failures to dominate", st.WriteCASFailures, st.ReadCASFailures)
+	}
+	if st.WriteCASFailures > st.EnqueueRetries || st.ReadCASFailures > st.DequeueRetries {
+		t.Fatalf("CAS failures exceed retries: %+v", st)
+	}
+}
+
+// TestCASFailuresCountAsRetries checks the split between the counters: a
+// lost race counts as a retry and a CAS failure, a stale index only as a
+// retry.
+func TestCASFailuresCountAsRetries(t *testing.T) {
+	rb := NewbufferOpts(8, WithMetrics(true))
+	rb.stats.addWriteCASFailure()
+	rb.stats.addEnqueueRetry()
+	rb.stats.addReadCASFailure()
+	want := Stats{EnqueueRetries: 2, DequeueRetries: 1, WriteCASFailures: 1, ReadCASFailures: 1}
+	if st := rb.Stats(); st != want {
+		t.Fatalf("Stats() = %+v, want %+v", st, want)
+	}
+}
+
+func TestContentionWarning(t *testing.T) {
+	var warnings []Stats
+	rb := NewbufferOpts(8, WithMetrics(true), WithContentionWarning(0.5, func(st Stats) {
+		warnings = append(warnings, st)
+	}))
+	rb.stats.addEnqueued(4 * contentionCheck)
+	for range contentionCheck {
+		rb.stats.addWriteCASFailure()
+	}
+	if len(warnings) != 0 {
+		t.Fatalf("warned at a ratio of 0.25: %+v", warnings)
+	}
+	for range contentionCheck {
+		rb.stats.addWriteCASFailure()
+	}
+	if len(warnings) != 0 {
+		t.Fatalf("warned at a ratio of 0.5: %+v", warnings)
+	}
+	for range contentionCheck {
+		rb.stats.addWriteCASFailure()
+	}
+	if len(warnings) != 1 || warnings[0].WriteCASFailures != 3*contentionCheck {
+		t.Fatalf("warnings at a ratio of 0.75 = %+v, want one with %d failures", warnings, 3*contentionCheck)
+	}
+
+	rb.Reset()
+	for range 3 * contentionCheck {
+		rb.stats.addWriteCASFailure()
+	}
+	if len(warnings) != 4 {
+		t.Fatalf("%d warnings after Reset, want the option kept and 3 more", len(warnings))
+	}
+}
+
+// TestContentionWarningOversubscribed oversubscribes producers for real and
+// expects the warning to fire. Like TestCASFailuresProducerHeavy it needs
+// two CPUs for any race to be lost.
+func TestContentionWarningOversubscribed(t *testing.T) {
+	if runtime.NumCPU() < 2 {
+		t.Skip("needs at least two CPUs to contend")
+	}
+	producers := 8 * runtime.GOMAXPROCS(0)
+	var warned atomic.Bool
+	rb := NewbufferOpts(1024, WithMetrics(true), WithContentionWarning(0.01, func(Stats) { warned.Store(true) }))
+
+	var wg sync.WaitGroup
+	wg.Add(producers)
+	for range producers {
+		go func() {
+			defer wg.Done()
+			var o Order
+			for i := uint64(0); i < 50_000 && !warned.Load(); i++ {
+				rb.Enqueue(i, 0, 0)
+				rb.DequeueInto(&o)
+			}
+		}()
+	}
+	wg.Wait()
+	if !warned.Load() {
+		t.Fatalf("no warning from %d producers: %+v", producers, rb.Stats())
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// TryEnqueue retries Enqueue with the buffer's WaitStrategy until it succeeds,
+// timeout elapses or the buffer is closed, and reports whether the item was
+// enqueued. The deadline is tracked by a timer that sets a flag rather than
+// by reading the clock on every retry, and a first attempt that succeeds
+// starts no timer at all. With a sleeping WaitStrategy the call can overrun
+// timeout by up to one sleep.
+func (rb *RingBuffer) TryEnqueue(id uint64, price float64, qty uint32, timeout time.Duration) bool {
+	return rb.retryFor(timeout, func() (bool, bool) {
+		return rb.Enqueue(id, price, qty), rb.Closed()
+	})
+}
+
+// TryDequeue is TryEnqueue for Dequeue. It gives up early once the buffer is
+// closed and drained.
+func (rb *RingBuffer) TryDequeue(timeout time.Duration) (Order, bool) {
+	var o Order
+	ok := rb.retryFor(timeout, func() (bool, bool) {
+		return rb.Dequeue(&o.ID, &o.Price, &o.Qty), rb.closedEmpty()
+	})
+	return o, ok
+}
+
+// DequeueBatchDeadline waits up to maxWait for a full batch of len(ids)
+// items, then settles for whatever is ready, and returns how many items it
+// took: a full batch as soon as one is available, otherwise between 0 and
+// len(ids) once maxWait elapses. It also stops waiting once the buffer is
+// closed, so the tail of a stream is still delivered. Like TryDequeue it
+// watches the deadline through a timer, not the clock. An empty ids returns
+// 0 at once.
+func (rb *RingBuffer) DequeueBatchDeadline(ids []uint64, prices []float64, qtys []uint32, maxWait time.Duration) uint64 {
+	want := min(len(ids), int(rb.capacity))
+	if want == 0 {
+		return 0
+	}
+	var n uint64
+	if rb.retryFor(maxWait, func() (bool, bool) {
+		n = rb.DequeueBatchMin(want, want, ids, prices, qtys)
+		return n > 0, rb.Closed()
+	}) {
+		return n
+	}
+	return rb.DequeueBatchUpTo(ids, prices, qtys)
+}
+
+// retryFor calls try until it reports success or that retrying is pointless,
+// or until timeout elapses.
+func (rb *RingBuffer) retryFor(timeout time.Duration, try func() (ok, done bool)) bool {
+	ok, done := try()
+	if ok || done || timeout <= 0 {
+		return ok
+	}
+
+	var expired atomic.Bool
+	timer := time.AfterFunc(timeout, func() { expired.Store(true) })
+	defer timer.Stop()
+
+	for attempt := 0; !expired.Load(); attempt++ {
+		rb.wait.Wait(attempt)
+		if ok, done = try(); ok || done {
+			return ok
+		}
+	}
+	return false
+}
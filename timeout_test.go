@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDequeueBatchDeadlineEmptyBatch(t *testing.T) {
+	rb := Newbuffer(8)
+	start := time.Now()
+	if n := rb.DequeueBatchDeadline(nil, nil, nil, time.Second); n != 0 {
+		t.Fatalf("DequeueBatchDeadline(nil) = %d, want 0", n)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("DequeueBatchDeadline(nil) waited %v", elapsed)
+	}
+}
+
+func TestDequeueBatchDeadlinePartial(t *testing.T) {
+	rb := Newbuffer(8)
+	rb.Enqueue(1, 0, 0)
+	rb.Enqueue(2, 0, 0)
+
+	ids := make([]uint64, 4)
+	n := rb.DequeueBatchDeadline(ids, make([]float64, 4), make([]uint32, 4), 10*time.Millisecond)
+	if n != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Fatalf("DequeueBatchDeadline = %d, %v, want 2 items 1 and 2", n, ids[:n])
+	}
+}
+
+// TestTryEnqueueTimeout checks that TryEnqueue gives up after roughly the
+// timeout on a buffer that stays full, and succeeds as soon as a consumer
+// frees a slot mid-wait.
+func TestTryEnqueueTimeout(t *testing.T) {
+	const timeout = 50 * time.Millisecond
+	rb := Newbuffer(2)
+	rb.Enqueue(1, 0, 0)
+	rb.Enqueue(2, 0, 0)
+
+	start := time.Now()
+	if rb.TryEnqueue(3, 0, 0, timeout) {
+		t.Fatal("TryEnqueue into a full buffer succeeded")
+	}
+	if elapsed := time.Since(start); elapsed < timeout || elapsed > 10*timeout {
+		t.Fatalf("TryEnqueue gave up after %v, want about %v", elapsed, timeout)
+	}
+
+	go func() {
+		time.Sleep(timeout / 5)
+		rb.DequeueOrder()
+	}()
+	start = time.Now()
+	if !rb.TryEnqueue(3, 0, 0, time.Minute) {
+		t.Fatal("TryEnqueue failed after a slot was freed")
+	}
+	if elapsed := time.Since(start); elapsed > 10*timeout {
+		t.Fatalf("TryEnqueue took %v to notice the freed slot", elapsed)
+	}
+}
+
+func TestTryDequeueTimeout(t *testing.T) {
+	const timeout = 50 * time.Millisecond
+	rb := Newbuffer(2)
+
+	start := time.Now()
+	if _, ok := rb.TryDequeue(timeout); ok {
+		t.Fatal("TryDequeue from an empty buffer succeeded")
+	}
+	if elapsed := time.Since(start); elapsed < timeout || elapsed > 10*timeout {
+		t.Fatalf("TryDequeue gave up after %v, want about %v", elapsed, timeout)
+	}
+
+	go func() {
+		time.Sleep(timeout / 5)
+		rb.Enqueue(7, 0, 0)
+	}()
+	if o, ok := rb.TryDequeue(time.Minute); !ok || o.ID != 7 {
+		t.Fatalf("TryDequeue = %+v, %v, want item 7", o, ok)
+	}
+
+	rb.Close()
+	start = time.Now()
+	if _, ok := rb.TryDequeue(time.Minute); ok || time.Since(start) > timeout {
+		t.Fatalf("TryDequeue on a closed, drained buffer = %v after %v, want false at once", ok, time.Since(start))
+	}
+}
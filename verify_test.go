@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+const (
+	verifyItems = 50_000
+	verifyBatch = 8
+)
+
+// TestExactlyOnce is the exact-set correctness check over every combination
+// of 1, 4 and 8 producers and consumers and capacities 16 and 1024. Each
+// producer enqueues a disjoint range of IDs, alternating single and batch
+// enqueues; each consumer collects what it dequeues, alternating single and
+// partial batch dequeues, into its own list. Afterwards the union of the
+// lists must be exactly the produced IDs, each seen once. Unlike the soak
+// test it is deterministic in shape, so it is the baseline to run after
+// every change, under the race detector:
+//
+//	go test -race -run ExactlyOnce
+func TestExactlyOnce(t *testing.T) {
+	items := verifyItems
+	if testing.Short() {
+		items /= 10
+	}
+	for _, capacity := range []uint64{16, 1024} {
+		for _, producers := range []int{1, 4, 8} {
+			for _, consumers := range []int{1, 4, 8} {
+				name := fmt.Sprintf("cap%d/P%d_C%d", capacity, producers, consumers)
+				t.Run(name, func(t *testing.T) {
+					if err := verifyExactlyOnce(capacity, producers, consumers, items); err != nil {
+						t.Fatal(err)
+					}
+				})
+			}
+		}
+	}
+}
+
+func verifyExactlyOnce(capacity uint64, producers, consumers, items int) error {
+	rb := Newbuffer(capacity)
+	total := uint64(producers * items)
+	var consumed atomic.Uint64
+
+	var wg sync.WaitGroup
+	wg.Add(producers)
+	for p := 0; p < producers; p++ {
+		go func() {
+			defer wg.Done()
+			ids := make([]uint64, verifyBatch)
+			prices := make([]float64, verifyBatch)
+			qtys := make([]uint32, verifyBatch)
+
+			next := uint64(p * items)
+			end := next + uint64(items)
+			for round := 0; next < end; round++ {
+				if round%2 == 0 {
+					for !rb.Enqueue(next, 0, 0) {
+						runtime.Gosched()
+					}
+					next++
+					continue
+				}
+
+				n := min(uint64(verifyBatch), end-next)
+				for i := range n {
+					ids[i] = next + i
+				}
+				for rb.EnqueueBatch(ids[:n], prices[:n], qtys[:n]) == 0 {
+					runtime.Gosched()
+				}
+				next += n
+			}
+		}()
+	}
+
+	got := make([][]uint64, consumers)
+	var consumerWg sync.WaitGroup
+	consumerWg.Add(consumers)
+	for c := 0; c < consumers; c++ {
+		go func() {
+			defer consumerWg.Done()
+			ids := make([]uint64, verifyBatch)
+			prices := make([]float64, verifyBatch)
+			qtys := make([]uint32, verifyBatch)
+
+			for round := 0; consumed.Load() < total; round++ {
+				var n uint64
+				if round%2 == 0 {
+					if rb.Dequeue(&ids[0], &prices[0], &qtys[0]) {
+						n = 1
+					}
+				} else {
+					n = rb.DequeueBatchUpTo(ids, prices, qtys)
+				}
+				if n == 0 {
+					runtime.Gosched()
+					continue
+				}
+				got[c] = append(got[c], ids[:n]...)
+				consumed.Add(n)
+			}
+		}()
+	}
+
+	wg.Wait()
+	consumerWg.Wait()
+
+	seen := make([]uint8, total)
+	for c, ids := range got {
+		for _, id := range ids {
+			if id >= total {
+				return fmt.Errorf("consumer %d got id %d, never produced", c, id)
+			}
+			if seen[id]++; seen[id] > 1 {
+				return fmt.Errorf("id %d consumed twice", id)
+			}
+		}
+	}
+	for id, n := range seen {
+		if n == 0 {
+			return fmt.Errorf("id %d never consumed", id)
+		}
+	}
+	if !rb.IsEmpty() {
+		return fmt.Errorf("%d items left in the buffer", rb.Len())
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"runtime"
+	"time"
+)
+
+// WaitStrategy decides what a blocking operation does between failed
+// attempts. attempt counts the consecutive failures so far, starting at 0.
+// Implementations must be safe for concurrent use.
+type WaitStrategy interface {
+	Wait(attempt int)
+}
+
+var (
+	// BusySpin never gives up the core; it only issues the CPU's spin-wait
+	// hint. Lowest latency, but burns a full core per waiting goroutine.
+	BusySpin WaitStrategy = busySpin{}
+
+	// Yield hands the processor back to the Go scheduler on every retry.
+	// This is the default.
+	Yield WaitStrategy = yield{}
+)
+
+type busySpin struct{}
+
+func (busySpin) Wait(int) {
+	procyield(activeSpinCycles)
+}
+
+type yield struct{}
+
+func (yield) Wait(int) {
+	runtime.Gosched()
+}
+
+type sleepBackoff struct {
+	min, max time.Duration
+}
+
+// SleepBackoff sleeps between retries, starting at min and doubling on each
+// consecutive failure up to max. It trades latency for near-zero CPU use while
+// the buffer stays full or empty.
+func SleepBackoff(min, max time.Duration) WaitStrategy {
+	return sleepBackoff{min: min, max: max}
+}
+
+func (s sleepBackoff) Wait(attempt int) {
+	time.Sleep(s.delay(attempt))
+}
+
+func (s sleepBackoff) delay(attempt int) time.Duration {
+	if attempt < 32 {
+		return min(s.min<<attempt, s.max)
+	}
+	return s.max
+}